@@ -1,28 +1,64 @@
 package authx
 
 import (
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/lascape/gopkg/envx"
+	"github.com/pkg/errors"
 )
 
 const (
-	NameJwt = "jwt"
+	NameJwt     = "jwt"
+	NameSession = "session"
+	NameJwe     = "jwe"
+)
+
+var (
+	ErrAuthNotFound = errors.New("auth not found")
+	ErrAuthExists   = errors.New("auth already registered")
 )
 
 func init() {
-	RegisterAuth(NameJwt, &JWT{})
+	RegisterAuth(NameJwt, NewJWT())
+	RegisterAuth(NameSession, NewSession(NewMemorySessionStore()))
+	RegisterAuth(NameJwe, NewJWEFromSecret(jweSecret))
+	envx.RegisterGuard("authx.default_secret", guardDefaultSecret)
+}
+
+// guardDefaultSecret 禁止生产环境使用默认密钥
+func guardDefaultSecret(env string) error {
+	if env != envx.Prod {
+		return nil
+	}
+	var names []string
+	if string(jwtSecret) == defaultSecret {
+		names = append(names, "PKG_AUTHX_JWT_SECRET")
+	}
+	if string(jweSecret) == defaultSecret {
+		names = append(names, "PKG_AUTHX_JWE_SECRET")
+	}
+	if len(names) > 0 {
+		return errors.Errorf("%s must not use the default secret in prod", strings.Join(names, ", "))
+	}
+	return nil
 }
 
-type manager struct {
+// Manager 按名称管理 Auth，同一进程内可以用不同名称注册不同配置的实例
+type Manager struct {
 	m    map[string]Auth
-	lock *sync.Mutex
+	lock sync.RWMutex
 }
 
-var defaultManager = &manager{
-	m:    make(map[string]Auth),
-	lock: new(sync.Mutex),
+func NewManager() *Manager {
+	return &Manager{m: make(map[string]Auth)}
 }
 
+// DefaultManager 是包级别的 Manager，RegisterAuth 与 GetAuth 使用它
+var DefaultManager = NewManager()
+
 // Auth 是一个认证接口，可以支持 Session 或 JWT
 type Auth interface {
 	// GenerateToken 用于生成认证 Token
@@ -32,32 +68,89 @@ type Auth interface {
 	ValidateToken(token string) (map[string]interface{}, error)
 }
 
-func RegisterAuth(name string, auth Auth) {
-	defaultManager.lock.Lock()
-	defer defaultManager.lock.Unlock()
-	if _, ok := defaultManager.m[name]; ok {
-		panic("auth already registered")
+// Register 注册 Auth，名称已存在时返回 ErrAuthExists
+func (m *Manager) Register(name string, auth Auth) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.m[name]; ok {
+		return errors.Wrap(ErrAuthExists, name)
 	}
-	defaultManager.m[name] = auth
+	m.m[name] = auth
+	return nil
 }
 
-func GetAuth(name string) Auth {
-	defaultManager.lock.Lock()
-	defer defaultManager.lock.Unlock()
+// MustRegister 注册 Auth，名称已存在时 panic
+func (m *Manager) MustRegister(name string, auth Auth) {
+	if err := m.Register(name, auth); err != nil {
+		panic(err)
+	}
+}
+
+// Set 注册或替换 Auth
+func (m *Manager) Set(name string, auth Auth) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.m[name] = auth
+}
 
-	auth, ok := defaultManager.m[name]
+// Get 获取 Auth，名称不存在时返回 ErrAuthNotFound
+func (m *Manager) Get(name string) (Auth, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	auth, ok := m.m[name]
 	if !ok {
-		return &defaultAuth{}
+		return nil, errors.Wrap(ErrAuthNotFound, name)
+	}
+	return auth, nil
+}
+
+// MustGet 获取 Auth，名称不存在时 panic
+func (m *Manager) MustGet(name string) Auth {
+	auth, err := m.Get(name)
+	if err != nil {
+		panic(err)
+	}
+	return auth
+}
+
+// Names 返回已注册的全部名称
+func (m *Manager) Names() []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	names := make([]string, 0, len(m.m))
+	for name := range m.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func RegisterAuth(name string, auth Auth) {
+	DefaultManager.MustRegister(name, auth)
+}
+
+// LookupAuth 获取 Auth，名称不存在时返回 ErrAuthNotFound
+func LookupAuth(name string) (Auth, error) {
+	return DefaultManager.Get(name)
+}
+
+// GetAuth 获取 Auth，名称不存在时返回的 Auth 会拒绝一切操作
+func GetAuth(name string) Auth {
+	auth, err := DefaultManager.Get(name)
+	if err != nil {
+		return &deniedAuth{err: err}
 	}
 	return auth
 }
 
-type defaultAuth struct{}
+type deniedAuth struct {
+	err error
+}
 
-func (*defaultAuth) GenerateToken(_ map[string]interface{}, _ time.Duration) (string, error) {
-	return "", nil
+func (a *deniedAuth) GenerateToken(_ map[string]interface{}, _ time.Duration) (string, error) {
+	return "", a.err
 }
 
-func (*defaultAuth) ValidateToken(_ string) (map[string]interface{}, error) {
-	return make(map[string]interface{}), nil
+func (a *deniedAuth) ValidateToken(_ string) (map[string]interface{}, error) {
+	return nil, a.err
 }
@@ -1,6 +1,7 @@
 package authx
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -34,6 +35,46 @@ func TestGenerateToken(t *testing.T) {
 			expired: -time.Minute * 30,
 			wantErr: true,
 		},
+		{
+			name:   "jwe success",
+			method: NameJwe,
+			kv: map[string]interface{}{
+				"username": "admin",
+				"password": "admin",
+			},
+			expired: time.Minute * 30,
+			wantErr: false,
+		},
+		{
+			name:   "jwe expired token",
+			method: NameJwe,
+			kv: map[string]interface{}{
+				"username": "admin",
+				"password": "admin",
+			},
+			expired: -time.Minute * 30,
+			wantErr: true,
+		},
+		{
+			name:   "session success",
+			method: NameSession,
+			kv: map[string]interface{}{
+				"username": "admin",
+				"password": "admin",
+			},
+			expired: time.Minute * 30,
+			wantErr: false,
+		},
+		{
+			name:   "session expired token",
+			method: NameSession,
+			kv: map[string]interface{}{
+				"username": "admin",
+				"password": "admin",
+			},
+			expired: -time.Minute * 30,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -57,3 +98,34 @@ func TestGenerateToken(t *testing.T) {
 		})
 	}
 }
+
+func TestManager(t *testing.T) {
+	m := NewManager()
+	m.MustRegister("admin", NewJWT(WithSecret([]byte("admin-secret")), WithTTL(time.Hour)))
+	m.MustRegister("api", NewJWT(WithSecret([]byte("api-secret")), WithTTL(time.Minute)))
+	if err := m.Register("api", NewJWT()); !errors.Is(err, ErrAuthExists) {
+		t.Fatalf("Register() error = %v, want %v", err, ErrAuthExists)
+	}
+	if _, err := m.Get("unknown"); !errors.Is(err, ErrAuthNotFound) {
+		t.Fatalf("Get() error = %v, want %v", err, ErrAuthNotFound)
+	}
+
+	token, err := m.MustGet("admin").GenerateToken(map[string]interface{}{"uid": "1"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := m.MustGet("admin").ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if exp := int64(kv["exp"].(float64)) - time.Now().Unix(); exp < 3590 {
+		t.Fatalf("ValidateToken() exp got = %d, want about an hour", exp)
+	}
+	if _, err = m.MustGet("api").ValidateToken(token); err == nil {
+		t.Fatal("ValidateToken() should reject a token signed with another secret")
+	}
+
+	if _, err = GetAuth("unknown").ValidateToken(token); !errors.Is(err, ErrAuthNotFound) {
+		t.Fatalf("GetAuth() unknown error = %v, want %v", err, ErrAuthNotFound)
+	}
+}
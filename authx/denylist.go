@@ -0,0 +1,94 @@
+package authx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrTokenRevoked = errors.New("token revoked")
+
+// Denylist 保存被提前吊销的 Token
+type Denylist interface {
+	// Revoke 吊销 jti 对应的 Token，ttl 为 Token 剩余的有效期
+	Revoke(ctx context.Context, jti string, ttl time.Duration) error
+
+	// Revoked 判断 jti 是否已被吊销
+	Revoked(ctx context.Context, jti string) (bool, error)
+
+	// RevokeSubject 吊销 subject 在 before 及之前签发的全部 Token
+	RevokeSubject(ctx context.Context, subject string, before time.Time, ttl time.Duration) error
+
+	// SubjectRevokedAt 返回 subject 最近一次被吊销的时间，没有则返回零值
+	SubjectRevokedAt(ctx context.Context, subject string) (time.Time, error)
+}
+
+type memoryDenylistEntry struct {
+	at        time.Time
+	expiresAt time.Time
+}
+
+// MemoryDenylist 是进程内的 Denylist，适用于单实例与测试
+type MemoryDenylist struct {
+	tokens   map[string]memoryDenylistEntry
+	subjects map[string]memoryDenylistEntry
+	lock     sync.Mutex
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{
+		tokens:   make(map[string]memoryDenylistEntry),
+		subjects: make(map[string]memoryDenylistEntry),
+	}
+}
+
+func (d *MemoryDenylist) Revoke(_ context.Context, jti string, ttl time.Duration) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.gc()
+	now := time.Now()
+	d.tokens[jti] = memoryDenylistEntry{at: now, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (d *MemoryDenylist) Revoked(_ context.Context, jti string) (bool, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	entry, ok := d.tokens[jti]
+	return ok && entry.expiresAt.After(time.Now()), nil
+}
+
+func (d *MemoryDenylist) RevokeSubject(_ context.Context, subject string, before time.Time, ttl time.Duration) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.gc()
+	d.subjects[subject] = memoryDenylistEntry{at: before, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (d *MemoryDenylist) SubjectRevokedAt(_ context.Context, subject string) (time.Time, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	entry, ok := d.subjects[subject]
+	if !ok || !entry.expiresAt.After(time.Now()) {
+		return time.Time{}, nil
+	}
+	return entry.at, nil
+}
+
+// gc 清理过期的记录，调用方需持有锁
+func (d *MemoryDenylist) gc() {
+	now := time.Now()
+	for jti, entry := range d.tokens {
+		if !entry.expiresAt.After(now) {
+			delete(d.tokens, jti)
+		}
+	}
+	for subject, entry := range d.subjects {
+		if !entry.expiresAt.After(now) {
+			delete(d.subjects, subject)
+		}
+	}
+}
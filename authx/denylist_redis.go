@@ -0,0 +1,43 @@
+package authx
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const redisDenylistPrefix = "authx:denylist:"
+
+// RedisDenylist 基于 redisx.Must 返回的客户端实现 Denylist，记录随 Token 过期自动删除
+type RedisDenylist struct {
+	client *redis.Client
+}
+
+func NewRedisDenylist(client *redis.Client) *RedisDenylist {
+	return &RedisDenylist{client: client}
+}
+
+func (d *RedisDenylist) Revoke(ctx context.Context, jti string, ttl time.Duration) error {
+	return d.client.Set(ctx, redisDenylistPrefix+"jti:"+jti, 1, ttl).Err()
+}
+
+func (d *RedisDenylist) Revoked(ctx context.Context, jti string) (bool, error) {
+	n, err := d.client.Exists(ctx, redisDenylistPrefix+"jti:"+jti).Result()
+	return n > 0, err
+}
+
+func (d *RedisDenylist) RevokeSubject(ctx context.Context, subject string, before time.Time, ttl time.Duration) error {
+	return d.client.Set(ctx, redisDenylistPrefix+"sub:"+subject, before.Unix(), ttl).Err()
+}
+
+func (d *RedisDenylist) SubjectRevokedAt(ctx context.Context, subject string) (time.Time, error) {
+	at, err := d.client.Get(ctx, redisDenylistPrefix+"sub:"+subject).Int64()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(at, 0), nil
+}
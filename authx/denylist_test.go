@@ -0,0 +1,50 @@
+package authx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestJWTRevoke(t *testing.T) {
+	mr := miniredis.RunT(t)
+	denylists := map[string]Denylist{
+		"memory": NewMemoryDenylist(),
+		"redis":  NewRedisDenylist(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	}
+	for name, dl := range denylists {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			j := NewJWT(WithDenylist(dl))
+
+			token, err := j.GenerateToken(map[string]interface{}{"sub": "u1"}, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			other, err := j.GenerateToken(map[string]interface{}{"sub": "u1"}, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = j.RevokeToken(ctx, token); err != nil {
+				t.Fatalf("RevokeToken() error = %v", err)
+			}
+			if _, err = j.ValidateToken(token); !errors.Is(err, ErrTokenRevoked) {
+				t.Fatalf("ValidateToken() error = %v, want %v", err, ErrTokenRevoked)
+			}
+			if _, err = j.ValidateToken(other); err != nil {
+				t.Fatalf("ValidateToken() other error = %v", err)
+			}
+
+			if err = j.RevokeSubject(ctx, "u1"); err != nil {
+				t.Fatalf("RevokeSubject() error = %v", err)
+			}
+			if _, err = j.ValidateToken(other); !errors.Is(err, ErrTokenRevoked) {
+				t.Fatalf("ValidateToken() after RevokeSubject error = %v, want %v", err, ErrTokenRevoked)
+			}
+		})
+	}
+}
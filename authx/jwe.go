@@ -0,0 +1,155 @@
+package authx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/lascape/gopkg/envx"
+	"github.com/pkg/errors"
+)
+
+var jweSecret = envx.Declare(envx.Var{
+	Name: "PKG_AUTHX_JWE_SECRET", Type: "string", Default: defaultSecret, Package: "authx", Secret: true,
+	Description: "默认 JWE 的密钥，实际使用其 sha256",
+}).Bytes()
+
+type jweHeader struct {
+	Alg string `json:"alg"`
+	Enc string `json:"enc"`
+	Kid string `json:"kid,omitempty"`
+}
+
+type JWEOption func(j *JWE)
+
+// WithJWEKeyID 设置加密密钥的 kid
+func WithJWEKeyID(kid string) JWEOption {
+	return func(j *JWE) {
+		j.kid = kid
+	}
+}
+
+// WithJWEDecryptionKey 添加只用于解密的旧密钥，用于密钥轮换
+func WithJWEDecryptionKey(kid string, key []byte) JWEOption {
+	return func(j *JWE) {
+		j.keys[kid] = key
+	}
+}
+
+// JWE 生成 dir+A256GCM 加密的 compact Token，客户端无法读取其中的 kv
+type JWE struct {
+	kid  string
+	key  []byte
+	keys map[string][]byte
+}
+
+// NewJWE 创建 JWE，key 必须为 32 字节
+func NewJWE(key []byte, opts ...JWEOption) *JWE {
+	j := &JWE{key: key, keys: make(map[string][]byte)}
+	for _, opt := range opts {
+		opt(j)
+	}
+	j.keys[j.kid] = key
+	return j
+}
+
+// NewJWEFromSecret 使用 sha256(secret) 作为密钥创建 JWE
+func NewJWEFromSecret(secret []byte, opts ...JWEOption) *JWE {
+	key := sha256.Sum256(secret)
+	return NewJWE(key[:], opts...)
+}
+
+func (j *JWE) GenerateToken(kv map[string]interface{}, expired time.Duration) (string, error) {
+	if kv == nil {
+		kv = make(map[string]interface{})
+	}
+	kv["exp"] = float64(time.Now().Add(expired).Unix())
+	payload, err := json.Marshal(kv)
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(jweHeader{Alg: "dir", Enc: "A256GCM", Kid: j.kid})
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(j.key)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(iv); err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(header)
+	sealed := gcm.Seal(nil, iv, payload, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return strings.Join([]string{
+		protected,
+		"",
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+func (j *JWE) ValidateToken(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 || parts[1] != "" {
+		return nil, errors.New("invalid token")
+	}
+	decoded := make([][]byte, 5)
+	for i, part := range parts {
+		b, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return nil, errors.New("invalid token")
+		}
+		decoded[i] = b
+	}
+	var header jweHeader
+	if err := json.Unmarshal(decoded[0], &header); err != nil {
+		return nil, errors.New("invalid token")
+	}
+	if header.Alg != "dir" || header.Enc != "A256GCM" {
+		return nil, errors.New("invalid token algorithm")
+	}
+	key, ok := j.keys[header.Kid]
+	if !ok {
+		return nil, errors.Errorf("unknown kid %s", header.Kid)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded[2]) != gcm.NonceSize() {
+		return nil, errors.New("invalid token")
+	}
+	payload, err := gcm.Open(nil, decoded[2], append(decoded[3], decoded[4]...), []byte(parts[0]))
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+	var claims map[string]interface{}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("invalid token")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || int64(exp) <= time.Now().Unix() {
+		return nil, errors.New("token is expired")
+	}
+	return claims, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("A256GCM requires a 32 byte key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,38 @@
+package authx
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWE(t *testing.T) {
+	old := NewJWEFromSecret([]byte("old"), WithJWEKeyID("k1"))
+	j := NewJWEFromSecret([]byte("new"), WithJWEKeyID("k2"), WithJWEDecryptionKey("k1", NewJWEFromSecret([]byte("old")).key))
+
+	token, err := j.GenerateToken(map[string]interface{}{"password": "admin"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range strings.Split(token, ".") {
+		b, _ := base64.RawURLEncoding.DecodeString(part)
+		if strings.Contains(string(b), "admin") {
+			t.Fatalf("token leaks claims: %s", b)
+		}
+	}
+
+	oldToken, err := old.GenerateToken(map[string]interface{}{"uid": "1"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kv, err := j.ValidateToken(oldToken); err != nil || kv["uid"] != "1" {
+		t.Fatalf("ValidateToken() old key got = %v, error = %v", kv, err)
+	}
+
+	parts := strings.Split(token, ".")
+	parts[3] = base64.RawURLEncoding.EncodeToString([]byte("tampered"))
+	if _, err = j.ValidateToken(strings.Join(parts, ".")); err == nil {
+		t.Fatal("ValidateToken() should reject a tampered token")
+	}
+}
@@ -0,0 +1,324 @@
+package authx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// JWK 是 RFC 7517 定义的单个公钥
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS 是 JWK 的集合文档
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK 将密钥的公钥部分转换为 JWK，对称密钥不能公开
+func NewJWK(key *Key) (JWK, error) {
+	jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+	switch pub := key.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return jwk, errors.Errorf("key %s has no public key", key.ID)
+	}
+	return jwk, nil
+}
+
+// Key 将 JWK 还原为只能验签的密钥
+func (k JWK) Key() (*Key, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid jwk n")
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid jwk e")
+		}
+		return NewPublicKey(k.Kid, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())})
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported jwk crv %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid jwk x")
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid jwk y")
+		}
+		return NewPublicKey(k.Kid, &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)})
+	case "OKP":
+		x, err := decode(k.X)
+		if err != nil || k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, errors.Errorf("invalid jwk %s", k.Kid)
+		}
+		return NewPublicKey(k.Kid, ed25519.PublicKey(x))
+	}
+	return nil, errors.Errorf("unsupported jwk kty %s", k.Kty)
+}
+
+// JWKS 返回集合中全部非对称密钥的公钥文档
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.Keys() {
+		jwk, err := NewJWK(key)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// JWKSHandler 输出 KeySet 的公钥，通常挂载在 /.well-known/jwks.json
+func JWKSHandler(ks *KeySet) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, ks.JWKS())
+	}
+}
+
+type JWKSOption func(a *JWKSAuth)
+
+// WithJWKSCacheTTL 设置 JWKS 的缓存时间，过期后在下一次验证时重新拉取
+func WithJWKSCacheTTL(ttl time.Duration) JWKSOption {
+	return func(a *JWKSAuth) {
+		a.ttl = ttl
+	}
+}
+
+// WithJWKSMinRefreshInterval 设置遇到未知 kid 时两次拉取的最小间隔，避免被伪造的 kid 打爆
+func WithJWKSMinRefreshInterval(d time.Duration) JWKSOption {
+	return func(a *JWKSAuth) {
+		a.minInterval = d
+	}
+}
+
+// WithJWKSTimeout 设置拉取 JWKS 的超时时间
+func WithJWKSTimeout(d time.Duration) JWKSOption {
+	return func(a *JWKSAuth) {
+		a.timeout = d
+	}
+}
+
+// WithJWKSHTTPClient 设置拉取 JWKS 使用的 HTTP 客户端，例如信任私有 CA。
+// 默认客户端会校验证书，不使用 httpx 跳过证书校验的全局客户端，否则中间人可以替换公钥
+func WithJWKSHTTPClient(c *http.Client) JWKSOption {
+	return func(a *JWKSAuth) {
+		a.client = c
+	}
+}
+
+// WithJWKSJWTOptions 设置验证 Token 时使用的 JWT 选项，如 WithIssuer、WithAudience、WithLeeway
+func WithJWKSJWTOptions(opts ...JWTOption) JWKSOption {
+	return func(a *JWKSAuth) {
+		a.jwtOpts = append(a.jwtOpts, opts...)
+	}
+}
+
+// JWKSAuth 通过远程 JWKS 验证 Token，只能验证不能签发。
+// 拉取在锁外进行且同一时刻只有一个请求在拉取；拉取失败时继续使用上一次成功的公钥，并按失败次数退避
+type JWKSAuth struct {
+	url         string
+	keys        *KeySet
+	jwt         *JWT
+	jwtOpts     []JWTOption
+	ttl         time.Duration
+	minInterval time.Duration
+	timeout     time.Duration
+	client      *http.Client
+	group       singleflight.Group
+	fetchedAt   time.Time // 上一次成功拉取的时间
+	attemptAt   time.Time // 上一次拉取的时间
+	retryAt     time.Time // 拉取失败后，在此之前不再重试
+	failures    int
+	lastErr     error
+	lock        sync.Mutex
+}
+
+func NewJWKSAuth(url string, opts ...JWKSOption) *JWKSAuth {
+	a := &JWKSAuth{
+		url:         url,
+		keys:        NewKeySet(),
+		ttl:         time.Hour,
+		minInterval: time.Minute,
+		timeout:     time.Second * 5,
+		client:      &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.jwt = NewJWT(append(a.jwtOpts, WithKeySet(a.keys))...)
+	return a
+}
+
+func (a *JWKSAuth) GenerateToken(_ map[string]interface{}, _ time.Duration) (string, error) {
+	return "", errors.New("jwks auth can only validate tokens")
+}
+
+func (a *JWKSAuth) ValidateToken(token string) (map[string]interface{}, error) {
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return nil, err
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	if err = a.ensure(kid); err != nil {
+		return nil, err
+	}
+	return a.jwt.ValidateToken(token)
+}
+
+// Refresh 立即重新拉取 JWKS，失败时保留原有的公钥
+func (a *JWKSAuth) Refresh(ctx context.Context) error {
+	return a.refresh(ctx)
+}
+
+// ensure 缓存过期或 kid 未知时重新拉取 JWKS，拉取失败但已有公钥时继续使用已有的公钥
+func (a *JWKSAuth) ensure(kid string) error {
+	a.lock.Lock()
+	now := time.Now()
+	need := false
+	switch {
+	case now.Before(a.retryAt):
+	case a.fetchedAt.IsZero() || now.Sub(a.fetchedAt) > a.ttl:
+		need = true
+	default:
+		_, ok := a.keys.Get(kid)
+		need = !ok && now.Sub(a.attemptAt) > a.minInterval
+	}
+	lastErr := a.lastErr
+	a.lock.Unlock()
+
+	if need {
+		lastErr = a.refresh(context.Background())
+	}
+	if lastErr != nil && len(a.keys.Keys()) == 0 {
+		return lastErr
+	}
+	if lastErr != nil && need {
+		logrus.Warnf("authx: refresh jwks %s failed, using the last good key set: %v", a.url, lastErr)
+	}
+	return nil
+}
+
+// refresh 合并并发的拉取请求，HTTP 请求不持有锁
+func (a *JWKSAuth) refresh(ctx context.Context) error {
+	_, err, _ := a.group.Do("", func() (interface{}, error) {
+		keys, err := a.fetch(ctx)
+		if err == nil && len(keys) == 0 {
+			// 空文档或全部公钥都无法使用时按失败处理，不能清空上一次成功的公钥
+			err = errors.New("fetch jwks: no usable keys")
+		}
+		a.lock.Lock()
+		defer a.lock.Unlock()
+		now := time.Now()
+		a.attemptAt = now
+		if err != nil {
+			a.failures++
+			a.lastErr = err
+			a.retryAt = now.Add(a.backoff())
+			return nil, err
+		}
+		a.keys.replace(keys...)
+		a.fetchedAt, a.retryAt, a.failures, a.lastErr = now, time.Time{}, 0, nil
+		return nil, nil
+	})
+	return err
+}
+
+// backoff 失败后的重试间隔从 1s 开始翻倍，最长为 minInterval，调用方需持有锁
+func (a *JWKSAuth) backoff() time.Duration {
+	d := time.Second
+	for i := 1; i < a.failures && d < a.minInterval; i++ {
+		d *= 2
+	}
+	if d > a.minInterval {
+		d = a.minInterval
+	}
+	return d
+}
+
+// maxJWKSSize JWKS 文档的最大长度
+const maxJWKSSize = 1 << 20
+
+func (a *JWKSAuth) fetch(ctx context.Context) ([]*Key, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch jwks")
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetch jwks")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+	var set JWKS
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&set); err != nil {
+		return nil, errors.Wrap(err, "fetch jwks")
+	}
+	keys := make([]*Key, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		key, err := jwk.Key()
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,198 @@
+package authx
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestJWKSAuth(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es384, err := NewECDSAKey("es-1", ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewKeySet(es384, NewHMACKey("hs-1", []byte("secret")))
+	issuer := NewJWT(WithKeySet(ks))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/.well-known/jwks.json", JWKSHandler(ks))
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	if set := ks.JWKS(); len(set.Keys) != 1 || set.Keys[0].Kid != "es-1" {
+		t.Fatalf("JWKS() got = %+v, want only es-1", set)
+	}
+
+	validator := NewJWKSAuth(srv.URL+"/.well-known/jwks.json", WithJWKSMinRefreshInterval(0))
+	token, err := issuer.GenerateToken(map[string]interface{}{"uid": "1"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = validator.ValidateToken(token); err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+
+	// 轮换后未知的 kid 会触发重新拉取
+	if err = ks.Rotate(NewEd25519Key("ed-1", edKey)); err != nil {
+		t.Fatal(err)
+	}
+	token, err = issuer.GenerateToken(map[string]interface{}{"uid": "2"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := validator.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() after rotation error = %v", err)
+	}
+	if kv["uid"] != "2" {
+		t.Fatalf("ValidateToken() got = %v", kv)
+	}
+
+	if _, err = validator.GenerateToken(nil, time.Minute); err == nil {
+		t.Fatal("GenerateToken() should not be supported")
+	}
+}
+
+func TestJWKSAuthOutage(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es256, err := NewECDSAKey("es-1", ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewKeySet(es256)
+	issuer := NewJWT(WithKeySet(ks))
+	token, err := issuer.GenerateToken(map[string]interface{}{"uid": "1"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		hits  int32
+		down  atomic.Bool
+		empty atomic.Bool
+		delay = time.Millisecond * 100
+	)
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/jwks", func(c *gin.Context) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(delay)
+		if down.Load() {
+			c.Status(http.StatusServiceUnavailable)
+			return
+		}
+		JWKSHandler(ks)(c)
+	})
+	engine.GET("/jwks-empty", func(c *gin.Context) {
+		if empty.Load() {
+			// 对称密钥无法从 JWK 还原
+			c.JSON(http.StatusOK, JWKS{Keys: []JWK{{Kty: "oct", Kid: "hs"}}})
+			return
+		}
+		JWKSHandler(ks)(c)
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	// 冷启动时并发的验证只拉取一次
+	validator := NewJWKSAuth(srv.URL+"/jwks", WithJWKSCacheTTL(time.Millisecond*50))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := validator.ValidateToken(token); err != nil {
+				t.Errorf("ValidateToken() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Fatalf("fetch count got = %d, want 1", n)
+	}
+
+	// 缓存过期后 JWKS 不可用，继续使用上一次的公钥，并且在退避期间不再拉取
+	down.Store(true)
+	time.Sleep(time.Millisecond * 60)
+	for i := 0; i < 3; i++ {
+		if _, err = validator.ValidateToken(token); err != nil {
+			t.Fatalf("ValidateToken() during outage error = %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Fatalf("fetch count during outage got = %d, want 2", n)
+	}
+	if err = validator.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh() during outage should fail")
+	}
+
+	// 返回的文档中没有可用的公钥时同样按失败处理，保留上一次的公钥
+	partial := NewJWKSAuth(srv.URL+"/jwks-empty", WithJWKSCacheTTL(time.Millisecond*50))
+	empty.Store(false)
+	if _, err = partial.ValidateToken(token); err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	empty.Store(true)
+	time.Sleep(time.Millisecond * 60)
+	if err = partial.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh() without usable keys should fail")
+	}
+	if _, err = partial.ValidateToken(token); err != nil {
+		t.Fatalf("ValidateToken() after empty jwks error = %v", err)
+	}
+	if len(partial.keys.Keys()) != 1 || partial.failures == 0 || partial.retryAt.IsZero() {
+		t.Fatalf("state after empty jwks got keys = %d, failures = %d", len(partial.keys.Keys()), partial.failures)
+	}
+
+	// 没有可用的公钥时返回拉取错误，退避期间直接失败
+	cold := NewJWKSAuth(srv.URL + "/jwks")
+	atomic.StoreInt32(&hits, 0)
+	for i := 0; i < 3; i++ {
+		if _, err = cold.ValidateToken(token); err == nil {
+			t.Fatal("ValidateToken() without keys should fail")
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Fatalf("fetch count without keys got = %d, want 1", n)
+	}
+}
+
+func TestJWKSAuthTLS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/jwks", JWKSHandler(NewKeySet()))
+	srv := httptest.NewTLSServer(engine)
+	defer srv.Close()
+
+	// 自签名证书的服务端不能被默认客户端信任，否则中间人可以提供自己的公钥
+	if err := NewJWKSAuth(srv.URL + "/jwks").Refresh(context.Background()); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("Refresh() from an untrusted tls server error = %v", err)
+	}
+	keys, err := NewJWKSAuth(srv.URL+"/jwks", WithJWKSHTTPClient(srv.Client())).fetch(context.Background())
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("fetch() with trusted client got = %d keys, error = %v", len(keys), err)
+	}
+}
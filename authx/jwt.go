@@ -1,35 +1,282 @@
 package authx
 
 import (
+	"context"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/lascape/gopkg/envx"
 	"github.com/pkg/errors"
 	"time"
 )
 
-var jwtSecret = envx.ValueByEnv("PKG_AUTHX_JWT_SECRET", "your_secret_key").Bytes()
+// defaultSecret 是 PKG_AUTHX_JWT_SECRET 与 PKG_AUTHX_JWE_SECRET 的默认值，生产环境禁止使用
+const defaultSecret = "your_secret_key"
 
-type JWT struct{}
+var jwtSecret = envx.Declare(envx.Var{
+	Name: "PKG_AUTHX_JWT_SECRET", Type: "string", Default: defaultSecret, Package: "authx", Secret: true,
+	Description: "默认 JWT 的 HMAC 密钥",
+}).Bytes()
+
+// ErrTokenTTLTooLong 签发的有效期超过 WithMaxTokenTTL，这样的 Token 在 RevokeSubject 的记录过期后会重新生效
+var ErrTokenTTLTooLong = errors.New("token ttl exceeds max token ttl")
+
+// defaultKeySet 未指定密钥时使用的 HS256 密钥，kid 为空以兼容历史 Token
+var defaultKeySet = NewKeySet(NewHMACKey("", jwtSecret))
+
+type JWTOption func(j *JWT)
+
+// WithKeySet 指定 JWT 使用的密钥集合，可在运行时对其 Rotate 实现密钥轮换
+func WithKeySet(ks *KeySet) JWTOption {
+	return func(j *JWT) {
+		j.keys = ks
+	}
+}
+
+// WithSigningKey 使用单把密钥创建密钥集合
+func WithSigningKey(key *Key) JWTOption {
+	return func(j *JWT) {
+		j.keys = NewKeySet(key)
+	}
+}
+
+// WithSecret 使用 HS256 与指定的密钥签名，用于在同一进程注册多个不同密钥的 JWT
+func WithSecret(secret []byte) JWTOption {
+	return WithSigningKey(NewHMACKey("", secret))
+}
+
+// WithTTL 设置默认有效期，GenerateToken 的 expired 为 0 时使用，默认 2 小时
+func WithTTL(ttl time.Duration) JWTOption {
+	return func(j *JWT) {
+		j.ttl = ttl
+	}
+}
+
+// WithDenylist 开启 Token 吊销，ValidateToken 会拒绝已被吊销的 Token
+func WithDenylist(dl Denylist) JWTOption {
+	return func(j *JWT) {
+		j.denylist = dl
+	}
+}
+
+// WithMaxTokenTTL 设置签发 Token 的最长有效期，超过时签发返回 ErrTokenTTLTooLong，RevokeSubject 的记录会保留这么久，默认 7 天
+func WithMaxTokenTTL(ttl time.Duration) JWTOption {
+	return func(j *JWT) {
+		j.maxTTL = ttl
+	}
+}
+
+// WithIssuer 签发时写入 iss，验证时要求 iss 一致
+func WithIssuer(iss string) JWTOption {
+	return func(j *JWT) {
+		j.issuer = iss
+	}
+}
+
+// WithAudience 签发时默认写入 aud，验证时要求 aud 包含该值，防止签给其他服务的 Token 被重放
+func WithAudience(aud string) JWTOption {
+	return func(j *JWT) {
+		j.audience = aud
+	}
+}
+
+// WithLeeway 设置校验 exp、nbf、iat 时允许的时钟偏差
+func WithLeeway(leeway time.Duration) JWTOption {
+	return func(j *JWT) {
+		j.leeway = leeway
+	}
+}
+
+// RegisteredClaims 签发 Token 时可指定的标准声明，为空的字段使用 JWT 的配置或不写入
+type RegisteredClaims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	NotBefore time.Time
+}
+
+type JWT struct {
+	keys     *KeySet
+	denylist Denylist
+	maxTTL   time.Duration
+	ttl      time.Duration
+	issuer   string
+	audience string
+	leeway   time.Duration
+}
+
+func NewJWT(opts ...JWTOption) *JWT {
+	j := &JWT{maxTTL: time.Hour * 24 * 7, ttl: time.Hour * 2}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// KeySet 返回当前使用的密钥集合
+func (j *JWT) KeySet() *KeySet {
+	if j.keys == nil {
+		return defaultKeySet
+	}
+	return j.keys
+}
 
 func (j *JWT) GenerateToken(kv map[string]interface{}, expired time.Duration) (string, error) {
+	return j.GenerateTokenWithClaims(RegisteredClaims{}, kv, expired)
+}
+
+// GenerateTokenWithClaims 签发 Token 并写入 sub、iss、aud、nbf 等标准声明
+func (j *JWT) GenerateTokenWithClaims(rc RegisteredClaims, kv map[string]interface{}, expired time.Duration) (string, error) {
+	key, err := j.KeySet().SigningKey()
+	if err != nil {
+		return "", err
+	}
+	if kv == nil {
+		kv = make(map[string]interface{})
+	}
+	if expired == 0 {
+		expired = j.ttl
+	}
+	if expired > j.maxTTL {
+		return "", ErrTokenTTLTooLong
+	}
+	now := time.Now()
 	claims := jwt.MapClaims(kv)
-	claims["exp"] = float64(time.Now().Add(expired).Unix())
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	claims["exp"] = float64(now.Add(expired).Unix())
+	claims["iat"] = float64(now.Unix())
+	claims["jti"] = uuid.NewString()
+	if rc.Subject != "" {
+		claims["sub"] = rc.Subject
+	}
+	if rc.Issuer == "" {
+		rc.Issuer = j.issuer
+	}
+	if rc.Issuer != "" {
+		claims["iss"] = rc.Issuer
+	}
+	if len(rc.Audience) == 0 && j.audience != "" {
+		rc.Audience = []string{j.audience}
+	}
+	if len(rc.Audience) == 1 {
+		claims["aud"] = rc.Audience[0]
+	} else if len(rc.Audience) > 1 {
+		claims["aud"] = rc.Audience
+	}
+	if !rc.NotBefore.IsZero() {
+		claims["nbf"] = float64(rc.NotBefore.Unix())
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.SignKey)
 }
 
 func (j *JWT) ValidateToken(token string) (map[string]interface{}, error) {
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return jwtSecret, nil
-	})
+	parsedToken, err := j.parser().Parse(token, j.keyFunc)
 	if err != nil {
 		return nil, err
 	}
-	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok && parsedToken.Valid {
-		return claims, nil
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok || !parsedToken.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if err = j.checkRevoked(context.Background(), claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// RevokeToken 吊销单个 Token，已过期的 Token 无需处理
+func (j *JWT) RevokeToken(ctx context.Context, token string) error {
+	if j.denylist == nil {
+		return errors.New("denylist not configured")
+	}
+	parsedToken, err := j.parser().Parse(token, j.keyFunc)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	claims := parsedToken.Claims.(jwt.MapClaims)
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return errors.New("token has no jti")
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return errors.New("token has no exp")
+	}
+	return j.denylist.Revoke(ctx, jti, time.Until(exp.Time))
+}
+
+// RevokeSubject 吊销 sub 为 subject 的全部已签发 Token，用于踢出用户的所有会话。
+// iat 精度为秒，同一秒内新签发的 Token 也会被拒绝
+func (j *JWT) RevokeSubject(ctx context.Context, subject string) error {
+	if j.denylist == nil {
+		return errors.New("denylist not configured")
+	}
+	return j.denylist.RevokeSubject(ctx, subject, time.Now(), j.maxTTL)
+}
+
+func (j *JWT) checkRevoked(ctx context.Context, claims jwt.MapClaims) error {
+	if j.denylist == nil {
+		return nil
+	}
+	if jti, _ := claims["jti"].(string); jti != "" {
+		revoked, err := j.denylist.Revoked(ctx, jti)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+	if sub, _ := claims["sub"].(string); sub != "" {
+		at, err := j.denylist.SubjectRevokedAt(ctx, sub)
+		if err != nil {
+			return err
+		}
+		iat, _ := claims.GetIssuedAt()
+		if !at.IsZero() && (iat == nil || iat.Unix() <= at.Unix()) {
+			return ErrTokenRevoked
+		}
+	}
+	return nil
+}
+
+// parser 按配置校验 exp、nbf、iat、iss 与 aud
+func (j *JWT) parser() *jwt.Parser {
+	opts := []jwt.ParserOption{
+		jwt.WithLeeway(j.leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	}
+	if j.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(j.issuer))
+	}
+	if j.audience != "" {
+		opts = append(opts, jwt.WithAudience(j.audience))
+	}
+	return jwt.NewParser(opts...)
+}
+
+// keyFunc 根据 Header 中的 kid 选择验签密钥，没有 kid 的 Token 使用当前签名密钥
+func (j *JWT) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := j.KeySet().Get(kid)
+	if !ok {
+		if kid != "" {
+			return nil, errors.Errorf("unknown kid %s", kid)
+		}
+		signing, err := j.KeySet().SigningKey()
+		if err != nil {
+			return nil, err
+		}
+		key = signing
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("invalid signing method")
 	}
-	return nil, errors.New("invalid token")
+	return key.VerifyKey, nil
 }
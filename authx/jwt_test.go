@@ -0,0 +1,157 @@
+package authx
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lascape/gopkg/envx"
+)
+
+func TestJWTKeyRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es256, err := NewECDSAKey("es-1", ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := NewKeySet(NewRSAKey("rs-1", rsaKey))
+	j := NewJWT(WithKeySet(ks))
+
+	var tokens []string
+	for _, next := range []*Key{es256, NewEd25519Key("ed-1", edKey), nil} {
+		token, err := j.GenerateToken(map[string]interface{}{"uid": "1"}, time.Minute)
+		if err != nil {
+			t.Fatalf("GenerateToken() error = %v", err)
+		}
+		tokens = append(tokens, token)
+		if next != nil {
+			if err = ks.Rotate(next); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for i, token := range tokens {
+		kv, err := j.ValidateToken(token)
+		if err != nil {
+			t.Fatalf("ValidateToken(%d) error = %v", i, err)
+		}
+		if kv["uid"] != "1" {
+			t.Fatalf("ValidateToken(%d) got = %v", i, kv)
+		}
+	}
+
+	ks.Remove("rs-1")
+	if _, err = j.ValidateToken(tokens[0]); err == nil {
+		t.Fatal("ValidateToken() should fail after the key was removed")
+	}
+
+	// 公钥不能被当作 HMAC 密钥使用
+	forged, err := NewJWT(WithSigningKey(NewHMACKey("es-1", []byte("secret")))).
+		GenerateToken(map[string]interface{}{"uid": "1"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = j.ValidateToken(forged); err == nil {
+		t.Fatal("ValidateToken() should reject a mismatched signing method")
+	}
+}
+
+func TestJWTRegisteredClaims(t *testing.T) {
+	issuer := NewJWT(WithIssuer("gateway"), WithAudience("order"))
+	order := NewJWT(WithIssuer("gateway"), WithAudience("order"), WithLeeway(time.Second*10))
+	user := NewJWT(WithIssuer("gateway"), WithAudience("user"))
+	other := NewJWT(WithIssuer("other"), WithAudience("order"))
+
+	tests := []struct {
+		name    string
+		rc      RegisteredClaims
+		expired time.Duration
+		auth    *JWT
+		wantErr bool
+	}{
+		{name: "same audience", expired: time.Minute, auth: order},
+		{name: "other audience", expired: time.Minute, auth: user, wantErr: true},
+		{name: "other issuer", expired: time.Minute, auth: other, wantErr: true},
+		{name: "explicit audience", rc: RegisteredClaims{Audience: []string{"user", "order"}}, expired: time.Minute, auth: user},
+		{name: "not before", rc: RegisteredClaims{NotBefore: time.Now().Add(time.Minute)}, expired: time.Hour, auth: order, wantErr: true},
+		{name: "within leeway", expired: -time.Second * 2, auth: order},
+		{name: "outside leeway", expired: -time.Minute, auth: order, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rc.Subject = "u1"
+			token, err := issuer.GenerateTokenWithClaims(tt.rc, nil, tt.expired)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kv, err := tt.auth.ValidateToken(token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && kv["sub"] != "u1" {
+				t.Fatalf("ValidateToken() got = %v", kv)
+			}
+		})
+	}
+}
+
+func TestJWTMaxTokenTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []JWTOption
+		expired time.Duration
+		wantErr error
+	}{
+		{name: "default max", expired: time.Hour * 24 * 7},
+		{name: "over default max", expired: time.Hour*24*7 + time.Second, wantErr: ErrTokenTTLTooLong},
+		{name: "custom max", opts: []JWTOption{WithMaxTokenTTL(time.Hour)}, expired: time.Hour},
+		{name: "over custom max", opts: []JWTOption{WithMaxTokenTTL(time.Hour)}, expired: time.Hour * 2, wantErr: ErrTokenTTLTooLong},
+		{name: "default ttl", expired: 0},
+		{name: "default ttl over max", opts: []JWTOption{WithMaxTokenTTL(time.Hour), WithTTL(time.Hour * 24)}, wantErr: ErrTokenTTLTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := NewJWT(tt.opts...)
+			token, err := j.GenerateToken(nil, tt.expired)
+			if err != tt.wantErr {
+				t.Fatalf("GenerateToken() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			// expired 为 0 且未设置 WithTTL 时不能签发已经过期的 Token
+			if _, err = j.ValidateToken(token); err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGuardDefaultSecret(t *testing.T) {
+	if err := guardDefaultSecret(envx.Dev); err != nil {
+		t.Fatalf("guardDefaultSecret(dev) error = %v", err)
+	}
+	if string(jwtSecret) == defaultSecret {
+		if err := guardDefaultSecret(envx.Prod); err == nil || !strings.Contains(err.Error(), "PKG_AUTHX_JWT_SECRET") {
+			t.Fatalf("guardDefaultSecret(prod) error = %v", err)
+		}
+	}
+}
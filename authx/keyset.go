@@ -0,0 +1,188 @@
+package authx
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pkg/errors"
+)
+
+// Key 是一把带 kid 的 JWT 密钥，SignKey 为空时只能用于验签
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	SignKey   interface{}
+	VerifyKey interface{}
+}
+
+// CanSign 判断该密钥是否可以用于签名
+func (k *Key) CanSign() bool {
+	return k.SignKey != nil
+}
+
+// NewHMACKey 创建 HS256 对称密钥
+func NewHMACKey(kid string, secret []byte) *Key {
+	return &Key{ID: kid, Method: jwt.SigningMethodHS256, SignKey: secret, VerifyKey: secret}
+}
+
+// NewRSAKey 创建 RS256 密钥
+func NewRSAKey(kid string, key *rsa.PrivateKey) *Key {
+	return &Key{ID: kid, Method: jwt.SigningMethodRS256, SignKey: key, VerifyKey: &key.PublicKey}
+}
+
+// NewECDSAKey 创建 ECDSA 密钥，根据曲线选择 ES256/ES384/ES512
+func NewECDSAKey(kid string, key *ecdsa.PrivateKey) (*Key, error) {
+	method, err := ecdsaMethod(key.Curve)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{ID: kid, Method: method, SignKey: key, VerifyKey: &key.PublicKey}, nil
+}
+
+// NewEd25519Key 创建 EdDSA 密钥
+func NewEd25519Key(kid string, key ed25519.PrivateKey) *Key {
+	return &Key{ID: kid, Method: jwt.SigningMethodEdDSA, SignKey: key, VerifyKey: key.Public()}
+}
+
+// NewPublicKey 创建只用于验签的公钥，算法由公钥类型推断
+func NewPublicKey(kid string, pub crypto.PublicKey) (*Key, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodRS256, VerifyKey: k}, nil
+	case *ecdsa.PublicKey:
+		method, err := ecdsaMethod(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		return &Key{ID: kid, Method: method, VerifyKey: k}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodEdDSA, VerifyKey: k}, nil
+	}
+	return nil, errors.Errorf("unsupported public key type %T", pub)
+}
+
+// NewKeyFromPEM 从 PEM 格式的私钥创建密钥，支持 RSA、ECDSA 与 Ed25519
+func NewKeyFromPEM(kid string, pem []byte) (*Key, error) {
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+		return NewRSAKey(kid, key), nil
+	}
+	if key, err := jwt.ParseECPrivateKeyFromPEM(pem); err == nil {
+		return NewECDSAKey(kid, key)
+	}
+	if key, err := jwt.ParseEdPrivateKeyFromPEM(pem); err == nil {
+		if k, ok := key.(ed25519.PrivateKey); ok {
+			return NewEd25519Key(kid, k), nil
+		}
+	}
+	return nil, errors.New("unsupported private key pem")
+}
+
+func ecdsaMethod(curve elliptic.Curve) (jwt.SigningMethod, error) {
+	switch curve {
+	case elliptic.P256():
+		return jwt.SigningMethodES256, nil
+	case elliptic.P384():
+		return jwt.SigningMethodES384, nil
+	case elliptic.P521():
+		return jwt.SigningMethodES512, nil
+	}
+	return nil, errors.Errorf("unsupported ecdsa curve %s", curve.Params().Name)
+}
+
+// KeySet 按 kid 管理一组密钥，其中一把为当前签名密钥。
+// 轮换时调用 Rotate 切换签名密钥，旧密钥仍保留用于验签，直到调用 Remove
+type KeySet struct {
+	keys    map[string]*Key
+	current *Key
+	lock    sync.RWMutex
+}
+
+func NewKeySet(keys ...*Key) *KeySet {
+	ks := &KeySet{keys: make(map[string]*Key)}
+	for _, key := range keys {
+		ks.Add(key)
+	}
+	return ks
+}
+
+// Add 添加一把密钥，若当前还没有签名密钥（或 kid 相同）且该密钥可签名，则作为签名密钥
+func (ks *KeySet) Add(key *Key) {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	ks.keys[key.ID] = key
+	if key.CanSign() && (ks.current == nil || ks.current.ID == key.ID) {
+		ks.current = key
+	}
+}
+
+// Rotate 添加新密钥并将其设为签名密钥
+func (ks *KeySet) Rotate(key *Key) error {
+	if !key.CanSign() {
+		return errors.Errorf("key %s cannot sign", key.ID)
+	}
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	ks.keys[key.ID] = key
+	ks.current = key
+	return nil
+}
+
+// Remove 删除密钥，使用该 kid 签发的 Token 将无法再通过验证
+func (ks *KeySet) Remove(kid string) {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	delete(ks.keys, kid)
+	if ks.current != nil && ks.current.ID == kid {
+		ks.current = nil
+	}
+}
+
+// Get 根据 kid 查找密钥
+func (ks *KeySet) Get(kid string) (*Key, bool) {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// SigningKey 返回当前签名密钥
+func (ks *KeySet) SigningKey() (*Key, error) {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	if ks.current == nil {
+		return nil, errors.New("no signing key")
+	}
+	return ks.current, nil
+}
+
+// Keys 返回全部密钥，按 kid 排序
+func (ks *KeySet) Keys() []*Key {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	keys := make([]*Key, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+// replace 用 keys 整体替换集合内容
+func (ks *KeySet) replace(keys ...*Key) {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	ks.keys = make(map[string]*Key, len(keys))
+	ks.current = nil
+	for _, key := range keys {
+		ks.keys[key.ID] = key
+		if ks.current == nil && key.CanSign() {
+			ks.current = key
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package authx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lascape/gopkg/response/ecode"
+	"github.com/lascape/gopkg/server/http/mid"
+	"github.com/lascape/gopkg/twofa"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrAccountLocked = errors.New("account locked")
+	ErrIPLocked      = errors.New("ip locked")
+	ErrTOTPInvalid   = errors.New("invalid totp code")
+)
+
+// AttemptStore 保存登录失败次数与锁定状态
+type AttemptStore interface {
+	// Incr 计数加一并返回当前值，计数从第一次增加起 ttl 后过期
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
+	// Lock 锁定 key，ttl 后自动解锁
+	Lock(ctx context.Context, key string, ttl time.Duration) error
+
+	// LockTTL 返回 key 剩余的锁定时长，未锁定时返回 0
+	LockTTL(ctx context.Context, key string) (time.Duration, error)
+
+	// Del 删除计数
+	Del(ctx context.Context, keys ...string) error
+}
+
+type memoryAttempt struct {
+	n         int64
+	expiresAt time.Time
+}
+
+// MemoryAttemptStore 是进程内的 AttemptStore，适用于单实例与测试，写入时顺带清理过期的计数与锁定
+type MemoryAttemptStore struct {
+	counters map[string]memoryAttempt
+	locks    map[string]time.Time
+	gcAt     time.Time
+	lock     sync.Mutex
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{
+		counters: make(map[string]memoryAttempt),
+		locks:    make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryAttemptStore) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.gc()
+	now := time.Now()
+	v, ok := s.counters[key]
+	if !ok || !v.expiresAt.After(now) {
+		v = memoryAttempt{expiresAt: now.Add(ttl)}
+	}
+	v.n++
+	s.counters[key] = v
+	return v.n, nil
+}
+
+func (s *MemoryAttemptStore) Lock(_ context.Context, key string, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.gc()
+	s.locks[key] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *MemoryAttemptStore) LockTTL(_ context.Context, key string) (time.Duration, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	until, ok := s.locks[key]
+	if !ok {
+		return 0, nil
+	}
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		delete(s.locks, key)
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (s *MemoryAttemptStore) Del(_ context.Context, keys ...string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, key := range keys {
+		delete(s.counters, key)
+	}
+	return nil
+}
+
+// gc 每隔 memoryGCInterval 清理一次过期的计数与锁定，调用方需持有锁
+func (s *MemoryAttemptStore) gc() {
+	now := time.Now()
+	if now.Sub(s.gcAt) < memoryGCInterval {
+		return
+	}
+	s.gcAt = now
+	for key, v := range s.counters {
+		if !v.expiresAt.After(now) {
+			delete(s.counters, key)
+		}
+	}
+	for key, until := range s.locks {
+		if !until.After(now) {
+			delete(s.locks, key)
+		}
+	}
+}
+
+type LoginLimiterOption func(l *LoginLimiter)
+
+// WithLoginAttemptStore 设置失败次数的存储，多实例部署时需要共享存储
+func WithLoginAttemptStore(store AttemptStore) LoginLimiterOption {
+	return func(l *LoginLimiter) {
+		l.store = store
+	}
+}
+
+// WithLoginMaxAttempts 设置窗口内账号与 IP 允许的最大失败次数，默认分别为 5 与 20，小于等于 0 表示不限制
+func WithLoginMaxAttempts(account, ip int64) LoginLimiterOption {
+	return func(l *LoginLimiter) {
+		l.maxAccount = account
+		l.maxIP = ip
+	}
+}
+
+// WithLoginWindow 设置失败次数的统计窗口，默认 15 分钟
+func WithLoginWindow(window time.Duration) LoginLimiterOption {
+	return func(l *LoginLimiter) {
+		l.window = window
+	}
+}
+
+// WithLoginLockout 设置锁定时长，第 n 次锁定的时长为 base*2^(n-1)，不超过 max，默认 1 分钟与 24 小时
+func WithLoginLockout(base, max time.Duration) LoginLimiterOption {
+	return func(l *LoginLimiter) {
+		l.lockBase = base
+		l.lockMax = max
+	}
+}
+
+// LoginLimiter 按账号与客户端 IP 统计登录失败次数，超过次数后按指数增长的时长锁定。
+// IP 通过 mid.ValueRealIP 从 ctx 中读取（路由上使用了 mid.XRealIp），ctx 为 *gin.Context 且没有该值时使用 ClientIP。
+// mid.XRealIp 直接信任请求中的 X-Forwarded-For，客户端每次换一个值就能绕过 IP 锁定，
+// 因此 IP 限制只有在服务部署于会覆盖 X-Forwarded-For 的可信代理之后才有意义；
+// 直接对外暴露时不要在登录路由上使用 mid.XRealIp，由 gin 的 SetTrustedProxies 决定 ClientIP，
+// 或用 WithLoginMaxAttempts(n, 0) 关闭 IP 限制，只按账号锁定
+type LoginLimiter struct {
+	store      AttemptStore
+	maxAccount int64
+	maxIP      int64
+	window     time.Duration
+	lockBase   time.Duration
+	lockMax    time.Duration
+}
+
+func NewLoginLimiter(opts ...LoginLimiterOption) *LoginLimiter {
+	l := &LoginLimiter{
+		maxAccount: 5,
+		maxIP:      20,
+		window:     time.Minute * 15,
+		lockBase:   time.Minute,
+		lockMax:    time.Hour * 24,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.store == nil {
+		l.store = NewMemoryAttemptStore()
+	}
+	return l
+}
+
+// Allow 在校验密码前调用，账号被锁定返回 ecode.ErrAccountLock，IP 被锁定返回 ecode.ErrLoginIP
+func (l *LoginLimiter) Allow(ctx context.Context, account string) error {
+	if ip := clientIP(ctx); ip != "" && l.maxIP > 0 {
+		ttl, err := l.store.LockTTL(ctx, "ip:"+ip)
+		if err != nil {
+			return err
+		}
+		if ttl > 0 {
+			return ecode.Wrap(ecode.ErrLoginIP, errors.Wrapf(ErrIPLocked, "retry after %s", ttl.Round(time.Second)))
+		}
+	}
+	if account != "" && l.maxAccount > 0 {
+		ttl, err := l.store.LockTTL(ctx, "account:"+account)
+		if err != nil {
+			return err
+		}
+		if ttl > 0 {
+			return ecode.Wrap(ecode.ErrAccountLock, errors.Wrapf(ErrAccountLocked, "retry after %s", ttl.Round(time.Second)))
+		}
+	}
+	return nil
+}
+
+// Fail 记录一次登录失败，达到次数时锁定并返回与 Allow 相同的错误
+func (l *LoginLimiter) Fail(ctx context.Context, account string) error {
+	if ip := clientIP(ctx); ip != "" && l.maxIP > 0 {
+		if err := l.fail(ctx, "ip:"+ip, l.maxIP); err != nil {
+			return err
+		}
+	}
+	if account != "" && l.maxAccount > 0 {
+		if err := l.fail(ctx, "account:"+account, l.maxAccount); err != nil {
+			return err
+		}
+	}
+	return l.Allow(ctx, account)
+}
+
+// Success 登录成功后清除账号的失败次数与锁定级别，IP 的计数不清除
+func (l *LoginLimiter) Success(ctx context.Context, account string) error {
+	return l.store.Del(ctx, "fail:account:"+account, "level:account:"+account)
+}
+
+// VerifyTOTP 校验二次验证码，错误的验证码与密码错误一样计入失败次数
+func (l *LoginLimiter) VerifyTOTP(ctx context.Context, account, secret, code string) error {
+	if err := l.Allow(ctx, account); err != nil {
+		return err
+	}
+	if twofa.Verify2faCode(secret, code) {
+		return nil
+	}
+	if err := l.Fail(ctx, account); err != nil {
+		return err
+	}
+	return ecode.Wrap(ecode.ErrLogin, ErrTOTPInvalid)
+}
+
+func (l *LoginLimiter) fail(ctx context.Context, key string, max int64) error {
+	n, err := l.store.Incr(ctx, "fail:"+key, l.window)
+	if err != nil || n < max {
+		return err
+	}
+	// 锁定级别保留到最长锁定时长之后，期间再次被锁定时长翻倍
+	level, err := l.store.Incr(ctx, "level:"+key, l.lockMax+l.window)
+	if err != nil {
+		return err
+	}
+	if err = l.store.Lock(ctx, key, l.lockout(level)); err != nil {
+		return err
+	}
+	return l.store.Del(ctx, "fail:"+key)
+}
+
+// clientIP 优先使用 mid.XRealIp 记录的地址，见 LoginLimiter 关于 X-Forwarded-For 的说明
+func clientIP(ctx context.Context) string {
+	if ip := mid.ValueRealIP(ctx); ip != "" {
+		return ip
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		return c.ClientIP()
+	}
+	return ""
+}
+
+func (l *LoginLimiter) lockout(level int64) time.Duration {
+	ttl := l.lockBase
+	for i := int64(1); i < level && ttl < l.lockMax; i++ {
+		ttl *= 2
+	}
+	if l.lockMax > 0 && ttl > l.lockMax {
+		ttl = l.lockMax
+	}
+	return ttl
+}
@@ -0,0 +1,58 @@
+package authx
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const redisAttemptPrefix = "authx:login:"
+
+// redisIncrScript 原子地计数并设置过期时间，没有过期时间的旧 key 也会被补上
+var redisIncrScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 or redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// RedisAttemptStore 基于 redisx.Must 返回的客户端实现 AttemptStore
+type RedisAttemptStore struct {
+	client *redis.Client
+}
+
+func NewRedisAttemptStore(client *redis.Client) *RedisAttemptStore {
+	return &RedisAttemptStore{client: client}
+}
+
+func (s *RedisAttemptStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return redisIncrScript.Run(ctx, s.client, []string{redisAttemptPrefix + key}, ttl.Milliseconds()).Int64()
+}
+
+func (s *RedisAttemptStore) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	return s.client.Set(ctx, redisAttemptPrefix+"lock:"+key, 1, ttl).Err()
+}
+
+func (s *RedisAttemptStore) LockTTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client.PTTL(ctx, redisAttemptPrefix+"lock:"+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (s *RedisAttemptStore) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	full := make([]string, 0, len(keys))
+	for _, key := range keys {
+		full = append(full, redisAttemptPrefix+key)
+	}
+	return s.client.Del(ctx, full...).Err()
+}
@@ -0,0 +1,171 @@
+package authx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/lascape/gopkg/response/ecode"
+	"github.com/lascape/gopkg/server/http/mid"
+	"github.com/lascape/gopkg/twofa"
+)
+
+func ipContext(ip string) context.Context {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+	ctx.Request.Header.Set("X-Forwarded-For", ip)
+	mid.XRealIp(ctx)
+	return ctx.Request.Context()
+}
+
+func errno(err error) *ecode.Errno {
+	var e *ecode.ErrorX
+	if errors.As(err, &e) {
+		return e.Errno
+	}
+	return nil
+}
+
+func TestLoginLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	stores := map[string]AttemptStore{
+		"memory": NewMemoryAttemptStore(),
+		"redis":  NewRedisAttemptStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			l := NewLoginLimiter(WithLoginAttemptStore(store), WithLoginMaxAttempts(3, 5),
+				WithLoginLockout(time.Minute, time.Minute*3))
+			ctx := ipContext("10.0.0.1")
+
+			for i := 0; i < 2; i++ {
+				if err := l.Fail(ctx, "alice"); err != nil {
+					t.Fatalf("Fail() #%d error = %v", i, err)
+				}
+			}
+			if err := l.Fail(ctx, "alice"); errno(err) != ecode.ErrAccountLock {
+				t.Fatalf("Fail() error = %v, want %v", err, ecode.ErrAccountLock)
+			}
+			if err := l.Allow(ctx, "alice"); errno(err) != ecode.ErrAccountLock {
+				t.Fatalf("Allow() error = %v, want %v", err, ecode.ErrAccountLock)
+			}
+			if err := l.Allow(ctx, "bob"); err != nil {
+				t.Fatalf("Allow() other account error = %v", err)
+			}
+
+			// 同一 IP 对不同账号的失败也会累计
+			if err := l.Fail(ctx, "bob"); err != nil {
+				t.Fatalf("Fail() bob error = %v", err)
+			}
+			if err := l.VerifyTOTP(ctx, "bob", twofa.RandomSecret(), "000000"); errno(err) != ecode.ErrLoginIP {
+				t.Fatalf("VerifyTOTP() error = %v, want %v", err, ecode.ErrLoginIP)
+			}
+			if err := l.Allow(ipContext("10.0.0.2"), "bob"); err != nil {
+				t.Fatalf("Allow() other ip error = %v", err)
+			}
+			if err := l.Success(ctx, "bob"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestLoginLimiterLockout(t *testing.T) {
+	l := NewLoginLimiter(WithLoginLockout(time.Minute, time.Minute*5))
+	tests := []struct {
+		level int64
+		want  time.Duration
+	}{
+		{level: 1, want: time.Minute},
+		{level: 2, want: time.Minute * 2},
+		{level: 3, want: time.Minute * 4},
+		{level: 4, want: time.Minute * 5},
+		{level: 60, want: time.Minute * 5},
+	}
+	for _, tt := range tests {
+		if got := l.lockout(tt.level); got != tt.want {
+			t.Errorf("lockout(%d) got = %v, want %v", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryAttemptStoreGC(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryAttemptStore()
+	for _, key := range []string{"fail:account:a", "fail:account:b"} {
+		if _, err := store.Incr(ctx, key, time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Lock(ctx, "account:a", time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 5)
+	store.gcAt = time.Time{}
+	if _, err := store.Incr(ctx, "fail:account:c", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.counters) != 1 || len(store.locks) != 0 {
+		t.Fatalf("gc got counters = %d, locks = %d", len(store.counters), len(store.locks))
+	}
+}
+
+func TestLoginLimiterClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	remote := func(addr string, withMid bool) context.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+		ctx.Request.RemoteAddr = addr
+		if !withMid {
+			return ctx
+		}
+		mid.XRealIp(ctx)
+		return ctx.Request.Context()
+	}
+	for _, withMid := range []bool{true, false} {
+		l := NewLoginLimiter(WithLoginMaxAttempts(0, 1))
+		// 没有 X-Forwarded-For 的客户端按各自的地址统计，不能落到同一个桶里
+		if err := l.Fail(remote("10.0.0.3:1234", withMid), ""); errno(err) != ecode.ErrLoginIP {
+			t.Fatalf("Fail() withMid = %v error = %v, want %v", withMid, err, ecode.ErrLoginIP)
+		}
+		if err := l.Allow(remote("10.0.0.4:1234", withMid), ""); err != nil {
+			t.Fatalf("Allow() withMid = %v other client error = %v", withMid, err)
+		}
+		if err := l.Allow(remote("10.0.0.3:4321", withMid), ""); errno(err) != ecode.ErrLoginIP {
+			t.Fatalf("Allow() withMid = %v same client error = %v", withMid, err)
+		}
+	}
+}
+
+func TestRedisAttemptStoreIncr(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	store := NewRedisAttemptStore(client)
+	ctx := context.Background()
+	for i := int64(1); i <= 2; i++ {
+		n, err := store.Incr(ctx, "fail:account:a", time.Minute)
+		if err != nil || n != i {
+			t.Fatalf("Incr() got = %d, error = %v", n, err)
+		}
+	}
+	if ttl := mr.TTL(redisAttemptPrefix + "fail:account:a"); ttl != time.Minute {
+		t.Fatalf("TTL got = %s", ttl)
+	}
+	// 之前 INCR 后进程退出留下的没有过期时间的 key
+	if err := client.Set(ctx, redisAttemptPrefix+"fail:account:b", 3, 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := store.Incr(ctx, "fail:account:b", time.Minute); err != nil || n != 4 {
+		t.Fatalf("Incr() got = %d, error = %v", n, err)
+	}
+	if ttl := mr.TTL(redisAttemptPrefix + "fail:account:b"); ttl != time.Minute {
+		t.Fatalf("TTL without expiry got = %s", ttl)
+	}
+}
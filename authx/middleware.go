@@ -0,0 +1,173 @@
+package authx
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lascape/gopkg/response"
+	"github.com/lascape/gopkg/response/ecode"
+)
+
+const (
+	ctxWithClaims = "ctx-with-auth-claims"
+	ctxWithToken  = "ctx-with-auth-token"
+)
+
+type middlewareOptions struct {
+	header   string
+	scheme   string
+	cookie   string
+	query    string
+	optional bool
+}
+
+type MiddlewareOption func(o *middlewareOptions)
+
+// WithTokenHeader 从请求头读取 Token，scheme 为空时整个值即为 Token，name 为空则不从请求头读取
+func WithTokenHeader(name, scheme string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.header = name
+		o.scheme = scheme
+	}
+}
+
+// WithTokenCookie 从 Cookie 读取 Token，name 为空则不从 Cookie 读取
+func WithTokenCookie(name string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.cookie = name
+	}
+}
+
+// WithTokenQuery 从 Query 参数读取 Token，name 为空则不从 Query 读取
+func WithTokenQuery(name string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.query = name
+	}
+}
+
+// WithOptional 没有携带 Token 时直接放行，携带了无效 Token 仍会被拒绝
+func WithOptional() MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.optional = true
+	}
+}
+
+// Middleware 依次从 Authorization: Bearer、Cookie token、Query token 中提取 Token 并用 auth 验证，
+// 验证通过后 claims 写入 gin.Context 与 Request.Context，可通过 Claims 等方法读取
+func Middleware(auth Auth, opts ...MiddlewareOption) gin.HandlerFunc {
+	o := &middlewareOptions{
+		header: "Authorization",
+		scheme: "Bearer",
+		cookie: "token",
+		query:  "token",
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(ctx *gin.Context) {
+		token := o.extract(ctx)
+		if token == "" {
+			if o.optional {
+				ctx.Next()
+				return
+			}
+			response.Error(ctx, ecode.ErrLogin)
+			ctx.Abort()
+			return
+		}
+		claims, err := auth.ValidateToken(token)
+		if err != nil {
+			response.Error(ctx, ecode.Wrap(ecode.ErrState, err))
+			ctx.Abort()
+			return
+		}
+		reqCtx := context.WithValue(ctx.Request.Context(), ctxWithClaims, claims)
+		ctx.Request = ctx.Request.WithContext(context.WithValue(reqCtx, ctxWithToken, token))
+		ctx.Set(ctxWithClaims, claims)
+		ctx.Set(ctxWithToken, token)
+		ctx.Next()
+	}
+}
+
+func (o *middlewareOptions) extract(ctx *gin.Context) string {
+	if o.header != "" {
+		v := strings.TrimSpace(ctx.GetHeader(o.header))
+		if o.scheme == "" && v != "" {
+			return v
+		}
+		if len(v) > len(o.scheme) && strings.EqualFold(v[:len(o.scheme)], o.scheme) && v[len(o.scheme)] == ' ' {
+			return strings.TrimSpace(v[len(o.scheme):])
+		}
+	}
+	if o.cookie != "" {
+		if v, err := ctx.Cookie(o.cookie); err == nil && v != "" {
+			return v
+		}
+	}
+	if o.query != "" {
+		if v := ctx.Query(o.query); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Claims 读取 Middleware 写入的 claims，未认证时返回 nil
+func Claims(ctx context.Context) map[string]interface{} {
+	if v, ok := ctx.Value(ctxWithClaims).(map[string]interface{}); ok {
+		return v
+	}
+	return nil
+}
+
+// Token 读取 Middleware 验证通过的原始 Token
+func Token(ctx context.Context) string {
+	if v, ok := ctx.Value(ctxWithToken).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// ClaimString 读取字符串类型的 claim
+func ClaimString(ctx context.Context, key string) string {
+	switch v := Claims(ctx)[key].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+// ClaimInt64 读取数字类型的 claim，JSON 解码得到的 float64 与数字字符串都会被转换
+func ClaimInt64(ctx context.Context, key string) int64 {
+	switch v := Claims(ctx)[key].(type) {
+	case float64:
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case json.Number:
+		i, _ := v.Int64()
+		return i
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	}
+	return 0
+}
+
+// BindClaims 将 claims 解析到结构体中，字段使用 json tag 对应 claim 名称
+func BindClaims(ctx context.Context, v interface{}) error {
+	b, err := json.Marshal(Claims(ctx))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,69 @@
+package authx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lascape/gopkg/response/ecode"
+)
+
+func TestMiddleware(t *testing.T) {
+	auth := GetAuth(NameJwt)
+	token, err := auth.GenerateToken(map[string]interface{}{"uid": 10, "name": "admin"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/me", Middleware(auth), func(ctx *gin.Context) {
+		var user struct {
+			Name string `json:"name"`
+		}
+		if err := BindClaims(ctx, &user); err != nil {
+			t.Error(err)
+		}
+		ctx.JSON(http.StatusOK, gin.H{"uid": ClaimInt64(ctx, "uid"), "name": user.Name})
+	})
+
+	tests := []struct {
+		name     string
+		header   string
+		query    string
+		wantCode int
+	}{
+		{name: "bearer header", header: "Bearer " + token, wantCode: ecode.Success.Code},
+		{name: "query", query: "?token=" + token, wantCode: ecode.Success.Code},
+		{name: "missing token", wantCode: ecode.ErrLogin.Code},
+		{name: "invalid token", header: "Bearer invalid", wantCode: ecode.ErrState.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			var body struct {
+				Code int    `json:"code"`
+				UID  int64  `json:"uid"`
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.wantCode {
+				t.Fatalf("code got = %d, want %d", body.Code, tt.wantCode)
+			}
+			if tt.wantCode == ecode.Success.Code && (body.UID != 10 || body.Name != "admin") {
+				t.Fatalf("claims got = %+v", body)
+			}
+		})
+	}
+}
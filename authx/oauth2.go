@@ -0,0 +1,316 @@
+package authx
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lascape/gopkg/httpx"
+	"github.com/lascape/gopkg/response"
+	"github.com/lascape/gopkg/response/ecode"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrOAuth2State   = errors.New("invalid oauth2 state")
+	ErrOAuth2IDToken = errors.New("invalid oauth2 id token")
+)
+
+// OAuth2Config 第三方 OAuth2/OIDC 提供方的配置
+type OAuth2Config struct {
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret" kms:"encode"`
+	AuthURL      string   `yaml:"auth_url"`
+	TokenURL     string   `yaml:"token_url"`
+	JWKSURL      string   `yaml:"jwks_url"`
+	Issuer       string   `yaml:"issuer"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+}
+
+// oauth2StateCookie 保存 state 的 Cookie，回调时要求与 query 中的 state 一致，防止登录 CSRF
+const oauth2StateCookie = "authx_oauth2_state"
+
+// oauth2StateTTL state 的有效期，即用户在第三方授权页停留的最长时间
+const oauth2StateTTL = time.Minute * 10
+
+// OAuth2State 是发起授权时保存的状态，在回调时取出
+type OAuth2State struct {
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+}
+
+// OAuth2StateStore 保存授权过程中的 state，每个 state 只能取出一次
+type OAuth2StateStore interface {
+	Save(ctx context.Context, state string, data *OAuth2State, ttl time.Duration) error
+
+	// Take 取出并删除 state，不存在或已过期时返回 nil
+	Take(ctx context.Context, state string) (*OAuth2State, error)
+}
+
+type memoryOAuth2State struct {
+	data      *OAuth2State
+	expiresAt time.Time
+}
+
+// MemoryOAuth2StateStore 是进程内的 OAuth2StateStore，适用于单实例与测试
+type MemoryOAuth2StateStore struct {
+	states map[string]memoryOAuth2State
+	lock   sync.Mutex
+}
+
+func NewMemoryOAuth2StateStore() *MemoryOAuth2StateStore {
+	return &MemoryOAuth2StateStore{states: make(map[string]memoryOAuth2State)}
+}
+
+func (s *MemoryOAuth2StateStore) Save(_ context.Context, state string, data *OAuth2State, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	for k, v := range s.states {
+		if !v.expiresAt.After(now) {
+			delete(s.states, k)
+		}
+	}
+	s.states[state] = memoryOAuth2State{data: data, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryOAuth2StateStore) Take(_ context.Context, state string) (*OAuth2State, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	v, ok := s.states[state]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.states, state)
+	if !v.expiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	return v.data, nil
+}
+
+type OAuth2Option func(c *OAuth2Client)
+
+// WithOAuth2StateStore 设置 state 存储，多实例部署时需要共享存储
+func WithOAuth2StateStore(store OAuth2StateStore) OAuth2Option {
+	return func(c *OAuth2Client) {
+		c.states = store
+	}
+}
+
+// WithOAuth2TokenTTL 设置登录成功后签发的本系统 Token 的有效期，默认 2 小时
+func WithOAuth2TokenTTL(ttl time.Duration) OAuth2Option {
+	return func(c *OAuth2Client) {
+		c.ttl = ttl
+	}
+}
+
+// WithOAuth2ClaimsMapper 将第三方 ID Token 的 claims 转换为本系统 Token 的 kv，
+// 可在这里查找或创建本地用户，返回错误则登录失败
+func WithOAuth2ClaimsMapper(f func(ctx context.Context, claims map[string]interface{}) (map[string]interface{}, error)) OAuth2Option {
+	return func(c *OAuth2Client) {
+		c.mapper = f
+	}
+}
+
+// OAuth2Client 实现 OAuth2 授权码 + PKCE 登录流程：校验第三方的 ID Token 后使用 auth 签发本系统的 Token
+type OAuth2Client struct {
+	conf     OAuth2Config
+	auth     Auth
+	states   OAuth2StateStore
+	idTokens *JWKSAuth
+	ttl      time.Duration
+	mapper   func(ctx context.Context, claims map[string]interface{}) (map[string]interface{}, error)
+}
+
+func NewOAuth2Client(conf OAuth2Config, auth Auth, opts ...OAuth2Option) *OAuth2Client {
+	c := &OAuth2Client{
+		conf:   conf,
+		auth:   auth,
+		ttl:    time.Hour * 2,
+		mapper: defaultOAuth2ClaimsMapper,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.states == nil {
+		c.states = NewMemoryOAuth2StateStore()
+	}
+	c.idTokens = NewJWKSAuth(conf.JWKSURL, WithJWKSJWTOptions(WithIssuer(conf.Issuer), WithAudience(conf.ClientID)))
+	return c
+}
+
+func defaultOAuth2ClaimsMapper(_ context.Context, claims map[string]interface{}) (map[string]interface{}, error) {
+	kv := map[string]interface{}{"sub": claims["sub"], "provider": claims["iss"]}
+	for _, key := range []string{"email", "name"} {
+		if v, ok := claims[key]; ok {
+			kv[key] = v
+		}
+	}
+	return kv, nil
+}
+
+// AuthCodeURL 生成跳转到第三方授权页的地址，state、PKCE verifier 与 nonce 保存在服务端。
+// 不使用 LoginHandler 时，调用方需要自行把 state 与浏览器绑定，并在回调时校验
+func (c *OAuth2Client) AuthCodeURL(ctx context.Context) (string, error) {
+	u, _, err := c.authCodeURL(ctx)
+	return u, err
+}
+
+func (c *OAuth2Client) authCodeURL(ctx context.Context) (string, string, error) {
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	if err = c.states.Save(ctx, state, &OAuth2State{Verifier: verifier, Nonce: nonce}, oauth2StateTTL); err != nil {
+		return "", "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.conf.ClientID},
+		"redirect_uri":          {c.conf.RedirectURL},
+		"scope":                 {strings.Join(c.scopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(c.conf.AuthURL, "?") {
+		sep = "&"
+	}
+	return c.conf.AuthURL + sep + params.Encode(), state, nil
+}
+
+// Exchange 使用授权码换取 Token 并校验 ID Token，返回 ID Token 的 claims
+func (c *OAuth2Client) Exchange(ctx context.Context, code, state string) (map[string]interface{}, error) {
+	saved, err := c.states.Take(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if saved == nil {
+		return nil, ErrOAuth2State
+	}
+	params := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.conf.RedirectURL},
+		"client_id":     {c.conf.ClientID},
+		"code_verifier": {saved.Verifier},
+	}
+	if c.conf.ClientSecret != "" {
+		params.Set("client_secret", c.conf.ClientSecret)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+		TokenType   string `json:"token_type"`
+	}
+	err = httpx.New(c.conf.TokenURL).
+		SetContentTypeFormUrlencoed().
+		SetHeader("Accept", "application/json").
+		SetBodyUrlValues(params).
+		MustCode(http.StatusOK).
+		Post(ctx).Unmarshal(&token).Error()
+	if err != nil {
+		return nil, errors.Wrap(err, "exchange oauth2 code")
+	}
+	if token.IDToken == "" {
+		return nil, errors.Wrap(ErrOAuth2IDToken, "id_token missing")
+	}
+	claims, err := c.idTokens.ValidateToken(token.IDToken)
+	if err != nil {
+		return nil, errors.Wrap(ErrOAuth2IDToken, err.Error())
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != saved.Nonce {
+		return nil, errors.Wrap(ErrOAuth2IDToken, "nonce mismatch")
+	}
+	return claims, nil
+}
+
+// Login 完成授权码交换并签发本系统的 Token
+func (c *OAuth2Client) Login(ctx context.Context, code, state string) (string, error) {
+	claims, err := c.Exchange(ctx, code, state)
+	if err != nil {
+		return "", err
+	}
+	kv, err := c.mapper(ctx, claims)
+	if err != nil {
+		return "", err
+	}
+	return c.auth.GenerateToken(kv, c.ttl)
+}
+
+// LoginHandler 重定向到第三方授权页，并将 state 写入 HttpOnly Cookie
+func (c *OAuth2Client) LoginHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		u, state, err := c.authCodeURL(ctx.Request.Context())
+		if err != nil {
+			response.Error(ctx, err)
+			return
+		}
+		c.setStateCookie(ctx, state, int(oauth2StateTTL/time.Second))
+		ctx.Redirect(http.StatusFound, u)
+	}
+}
+
+// CallbackHandler 处理第三方回调，要求 state 与 LoginHandler 写入的 Cookie 一致，
+// 成功后返回 {"token": "..."}，失败返回 ecode.ErrLogin
+func (c *OAuth2Client) CallbackHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if e := ctx.Query("error"); e != "" {
+			response.Error(ctx, ecode.Wrap(ecode.ErrLogin, errors.New(e+": "+ctx.Query("error_description"))))
+			return
+		}
+		state := ctx.Query("state")
+		cookie, _ := ctx.Cookie(oauth2StateCookie)
+		c.setStateCookie(ctx, "", -1)
+		if state == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+			response.Error(ctx, ecode.Wrap(ecode.ErrLogin, ErrOAuth2State))
+			return
+		}
+		token, err := c.Login(ctx.Request.Context(), ctx.Query("code"), state)
+		if err != nil {
+			response.Error(ctx, ecode.Wrap(ecode.ErrLogin, err))
+			return
+		}
+		response.Success(ctx, gin.H{"token": token})
+	}
+}
+
+// setStateCookie Cookie 只在回调地址下发送，SameSite=Lax 保证第三方跳转回来时仍会带上
+func (c *OAuth2Client) setStateCookie(ctx *gin.Context, state string, maxAge int) {
+	path, secure := "/", false
+	if u, err := url.Parse(c.conf.RedirectURL); err == nil {
+		if u.Path != "" {
+			path = u.Path
+		}
+		secure = u.Scheme == "https"
+	}
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oauth2StateCookie, state, maxAge, path, "", secure, true)
+}
+
+func (c *OAuth2Client) scopes() []string {
+	if len(c.conf.Scopes) == 0 {
+		return []string{"openid", "profile", "email"}
+	}
+	return c.conf.Scopes
+}
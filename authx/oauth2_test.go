@@ -0,0 +1,120 @@
+package authx
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lascape/gopkg/response/ecode"
+)
+
+func TestOAuth2Login(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := NewKeySet(NewRSAKey("provider-1", rsaKey))
+
+	// 模拟第三方提供方：授权页由测试直接解析，这里只实现 token 与 jwks 接口
+	var challenge, nonce string
+	gin.SetMode(gin.TestMode)
+	provider := gin.New()
+	srv := httptest.NewServer(provider)
+	defer srv.Close()
+	idTokens := NewJWT(WithKeySet(ks), WithIssuer(srv.URL), WithAudience("client-1"))
+	provider.GET("/jwks", JWKSHandler(ks))
+	provider.POST("/token", func(ctx *gin.Context) {
+		sum := sha256.Sum256([]byte(ctx.PostForm("code_verifier")))
+		if ctx.PostForm("code") != "code-1" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+			return
+		}
+		idToken, err := idTokens.GenerateTokenWithClaims(RegisteredClaims{Subject: "p-42"},
+			map[string]interface{}{"nonce": nonce, "email": "a@example.com"}, time.Minute)
+		if err != nil {
+			t.Error(err)
+		}
+		ctx.JSON(http.StatusOK, gin.H{"access_token": "at", "id_token": idToken, "token_type": "Bearer"})
+	})
+
+	auth := NewJWT(WithSecret([]byte("local")))
+	client := NewOAuth2Client(OAuth2Config{
+		ClientID:    "client-1",
+		AuthURL:     srv.URL + "/authorize",
+		TokenURL:    srv.URL + "/token",
+		JWKSURL:     srv.URL + "/jwks",
+		Issuer:      srv.URL,
+		RedirectURL: "http://localhost/callback",
+	}, auth)
+
+	app := gin.New()
+	app.GET("/login", client.LoginHandler())
+	app.GET("/callback", client.CallbackHandler())
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	parsed, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	challenge, nonce = query.Get("code_challenge"), query.Get("nonce")
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != "client-1" {
+		t.Fatalf("LoginHandler() location got = %s", parsed)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != query.Get("state") || !cookies[0].HttpOnly || cookies[0].Path != "/callback" {
+		t.Fatalf("LoginHandler() cookies got = %+v", cookies)
+	}
+
+	callback := func(state string, cookie *http.Cookie) (int, string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/callback?code=code-1&state="+url.QueryEscape(state), nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		app.ServeHTTP(w, req)
+		var body struct {
+			Code int `json:"code"`
+			Data struct {
+				Token string `json:"token"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Code, body.Data.Token
+	}
+
+	// 攻击者把自己的 state 发给受害者：浏览器中没有对应的 Cookie
+	if code, _ := callback(query.Get("state"), nil); code != ecode.ErrLogin.Code {
+		t.Fatalf("callback without cookie code got = %d, want %d", code, ecode.ErrLogin.Code)
+	}
+	if code, _ := callback(query.Get("state"), &http.Cookie{Name: oauth2StateCookie, Value: "other"}); code != ecode.ErrLogin.Code {
+		t.Fatalf("callback with other cookie code got = %d, want %d", code, ecode.ErrLogin.Code)
+	}
+
+	code, token := callback(query.Get("state"), cookies[0])
+	if code != ecode.Success.Code {
+		t.Fatalf("callback code got = %d", code)
+	}
+	kv, err := auth.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if kv["sub"] != "p-42" || kv["email"] != "a@example.com" {
+		t.Fatalf("ValidateToken() got = %v", kv)
+	}
+
+	// state 只能使用一次
+	if code, _ = callback(query.Get("state"), cookies[0]); code != ecode.ErrLogin.Code {
+		t.Fatalf("replayed callback code got = %d, want %d", code, ecode.ErrLogin.Code)
+	}
+}
@@ -0,0 +1,142 @@
+package password
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+
+	"github.com/lascape/gopkg/helpers"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidHash = errors.New("invalid password hash")
+	ErrUnknownHash = errors.New("unknown password hash")
+)
+
+// Hasher 是一种密码哈希算法，编码结果为 PHC 格式字符串
+type Hasher interface {
+	// Hash 生成带随机盐的编码哈希
+	Hash(password string) (string, error)
+
+	// Verify 以常量时间比较密码与编码哈希
+	Verify(password, encoded string) (bool, error)
+
+	// Supports 判断编码哈希是否由该算法生成
+	Supports(encoded string) bool
+
+	// NeedsRehash 判断编码哈希的参数是否与当前配置不同
+	NeedsRehash(encoded string) bool
+}
+
+type Option func(m *Manager)
+
+// WithHasher 设置生成新哈希使用的算法，默认 DefaultArgon2id
+func WithHasher(h Hasher) Option {
+	return func(m *Manager) {
+		m.current = h
+	}
+}
+
+// WithVerifier 添加只用于校验的算法，例如迁移期间仍需要校验的旧算法
+func WithVerifier(h Hasher) Option {
+	return func(m *Manager) {
+		m.hashers = append(m.hashers, h)
+	}
+}
+
+// WithLegacyMD5 是否校验 helpers.MD5 生成的无盐哈希，默认开启
+func WithLegacyMD5(enable bool) Option {
+	return func(m *Manager) {
+		m.md5 = enable
+	}
+}
+
+// Manager 使用当前算法生成哈希，并可校验其它已知算法与旧的 MD5 哈希
+type Manager struct {
+	current Hasher
+	hashers []Hasher
+	md5     bool
+}
+
+func NewManager(opts ...Option) *Manager {
+	m := &Manager{
+		current: DefaultArgon2id,
+		hashers: []Hasher{DefaultArgon2id, DefaultBcrypt},
+		md5:     true,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Default 是包级别的 Manager
+var Default = NewManager()
+
+func (m *Manager) Hash(password string) (string, error) {
+	return m.current.Hash(password)
+}
+
+// Verify 校验密码，ok 为 true 且 newHash 不为空时调用方应保存 newHash 以完成升级
+func (m *Manager) Verify(password, encoded string) (ok bool, newHash string, err error) {
+	if m.md5 && isMD5(encoded) {
+		ok = subtle.ConstantTimeCompare([]byte(helpers.MD5(password)), []byte(strings.ToLower(encoded))) == 1
+	} else {
+		h := m.hasher(encoded)
+		if h == nil {
+			return false, "", ErrUnknownHash
+		}
+		if ok, err = h.Verify(password, encoded); err != nil {
+			return false, "", err
+		}
+	}
+	if !ok || !m.NeedsRehash(encoded) {
+		return ok, "", nil
+	}
+	newHash, err = m.current.Hash(password)
+	if err != nil {
+		return false, "", err
+	}
+	return true, newHash, nil
+}
+
+// NeedsRehash 判断编码哈希是否需要用当前算法与参数重新生成
+func (m *Manager) NeedsRehash(encoded string) bool {
+	return !m.current.Supports(encoded) || m.current.NeedsRehash(encoded)
+}
+
+func (m *Manager) hasher(encoded string) Hasher {
+	if m.current.Supports(encoded) {
+		return m.current
+	}
+	for _, h := range m.hashers {
+		if h.Supports(encoded) {
+			return h
+		}
+	}
+	return nil
+}
+
+func isMD5(encoded string) bool {
+	if len(encoded) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(encoded)
+	return err == nil
+}
+
+// Hash 使用 Default 生成哈希
+func Hash(password string) (string, error) {
+	return Default.Hash(password)
+}
+
+// Verify 使用 Default 校验密码
+func Verify(password, encoded string) (bool, string, error) {
+	return Default.Verify(password, encoded)
+}
+
+// NeedsRehash 使用 Default 判断是否需要重新生成哈希
+func NeedsRehash(encoded string) bool {
+	return Default.NeedsRehash(encoded)
+}
@@ -0,0 +1,108 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lascape/gopkg/helpers"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerify(t *testing.T) {
+	fast := &Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	m := NewManager(WithHasher(fast))
+
+	argonHash, err := m.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(argonHash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("Hash() got = %s", argonHash)
+	}
+	oldArgonHash, err := (&Argon2id{Memory: 512, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}).Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := (&Bcrypt{Cost: bcrypt.MinCost}).Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		password   string
+		encoded    string
+		wantOk     bool
+		wantRehash bool
+		wantErr    bool
+	}{
+		{name: "argon2id", password: "secret", encoded: argonHash, wantOk: true},
+		{name: "argon2id wrong password", password: "wrong", encoded: argonHash},
+		{name: "argon2id old params", password: "secret", encoded: oldArgonHash, wantOk: true, wantRehash: true},
+		{name: "bcrypt", password: "secret", encoded: bcryptHash, wantOk: true, wantRehash: true},
+		{name: "bcrypt wrong password", password: "wrong", encoded: bcryptHash},
+		{name: "legacy md5", password: "secret", encoded: helpers.MD5("secret"), wantOk: true, wantRehash: true},
+		{name: "legacy md5 upper", password: "secret", encoded: strings.ToUpper(helpers.MD5("secret")), wantOk: true, wantRehash: true},
+		{name: "legacy md5 wrong password", password: "wrong", encoded: helpers.MD5("secret")},
+		{name: "unknown", password: "secret", encoded: "plain", wantErr: true},
+		{name: "broken argon2id", password: "secret", encoded: "$argon2id$v=19$m=x$a$b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, newHash, err := m.Verify(tt.password, tt.encoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOk {
+				t.Fatalf("Verify() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if (newHash != "") != tt.wantRehash {
+				t.Fatalf("Verify() newHash = %q, wantRehash %v", newHash, tt.wantRehash)
+			}
+			if newHash == "" {
+				return
+			}
+			if m.NeedsRehash(newHash) {
+				t.Fatalf("NeedsRehash() new hash %s", newHash)
+			}
+			if ok, _, err = m.Verify(tt.password, newHash); !ok || err != nil {
+				t.Fatalf("Verify() new hash ok = %v, error = %v", ok, err)
+			}
+		})
+	}
+
+	if _, _, err = NewManager(WithLegacyMD5(false)).Verify("secret", helpers.MD5("secret")); err != ErrUnknownHash {
+		t.Fatalf("Verify() legacy md5 disabled error = %v, want %v", err, ErrUnknownHash)
+	}
+}
+
+func TestArgon2idMalformed(t *testing.T) {
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "zero iterations", encoded: "$argon2id$v=19$m=1024,t=0,p=1$" + salt + "$" + key},
+		{name: "too many iterations", encoded: "$argon2id$v=19$m=1024,t=1000000,p=1$" + salt + "$" + key},
+		{name: "zero parallelism", encoded: "$argon2id$v=19$m=1024,t=1,p=0$" + salt + "$" + key},
+		{name: "parallelism overflow", encoded: "$argon2id$v=19$m=1024,t=1,p=256$" + salt + "$" + key},
+		{name: "zero memory", encoded: "$argon2id$v=19$m=0,t=1,p=1$" + salt + "$" + key},
+		{name: "huge memory", encoded: "$argon2id$v=19$m=4294967295,t=1,p=1$" + salt + "$" + key},
+		{name: "empty salt", encoded: "$argon2id$v=19$m=1024,t=1,p=1$$" + key},
+		{name: "empty key", encoded: "$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$"},
+		{name: "bad version", encoded: "$argon2id$v=16$m=1024,t=1,p=1$" + salt + "$" + key},
+		{name: "missing segment", encoded: "$argon2id$v=19$m=1024,t=1,p=1$" + salt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := DefaultArgon2id.Verify("secret", tt.encoded)
+			if ok || !errors.Is(err, ErrInvalidHash) {
+				t.Fatalf("Verify() ok = %v, error = %v, want %v", ok, err, ErrInvalidHash)
+			}
+			if !DefaultArgon2id.NeedsRehash(tt.encoded) {
+				t.Fatal("NeedsRehash() should be true")
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package authx
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lascape/gopkg/response"
+	"github.com/lascape/gopkg/response/ecode"
+)
+
+// DefaultRBAC 是包级别的授权实例，RequirePermission 与 RequireRole 使用它
+var DefaultRBAC = NewRBAC()
+
+type rbacRole struct {
+	parents []string
+	perms   map[string]struct{}
+}
+
+type RBACOption func(r *RBAC)
+
+// WithRolesKey 指定 claims 中保存角色列表的 key，默认为 roles
+func WithRolesKey(key string) RBACOption {
+	return func(r *RBAC) {
+		r.rolesKey = key
+	}
+}
+
+// RBAC 基于角色的授权，角色可以继承父角色的权限。
+// 权限的格式为 resource:action，支持通配符：`order:*` 匹配 order 下的全部权限，
+// `*:read` 匹配任意资源的 read，单独的 `*` 匹配一切
+type RBAC struct {
+	roles    map[string]*rbacRole
+	rolesKey string
+	lock     sync.RWMutex
+}
+
+func NewRBAC(opts ...RBACOption) *RBAC {
+	r := &RBAC{roles: make(map[string]*rbacRole), rolesKey: "roles"}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// AddRole 添加角色并设置其继承的父角色
+func (r *RBAC) AddRole(name string, parents ...string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.role(name).parents = parents
+}
+
+// Grant 为角色授予权限
+func (r *RBAC) Grant(name string, perms ...string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	role := r.role(name)
+	for _, perm := range perms {
+		role.perms[perm] = struct{}{}
+	}
+}
+
+// Revoke 收回角色的权限
+func (r *RBAC) Revoke(name string, perms ...string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	role := r.role(name)
+	for _, perm := range perms {
+		delete(role.perms, perm)
+	}
+}
+
+// Allowed 判断角色列表中是否有任一角色（包括继承的角色）拥有 perm
+func (r *RBAC) Allowed(roles []string, perm string) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	visited := make(map[string]bool)
+	for _, name := range roles {
+		if r.allowed(name, perm, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// Can 使用 Middleware 写入的 claims 中的角色判断权限
+func (r *RBAC) Can(ctx context.Context, perm string) bool {
+	return r.Allowed(r.Roles(ctx), perm)
+}
+
+// Roles 从 claims 中读取角色列表，支持数组与逗号分隔的字符串
+func (r *RBAC) Roles(ctx context.Context) []string {
+	switch v := Claims(ctx)[r.rolesKey].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		roles := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+		return roles
+	case string:
+		var roles []string
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				roles = append(roles, s)
+			}
+		}
+		return roles
+	}
+	return nil
+}
+
+// RequirePermission 要求当前用户拥有全部 perms，需放在 Middleware 之后
+func (r *RBAC) RequirePermission(perms ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if Claims(ctx) == nil {
+			response.Error(ctx, ecode.ErrLogin)
+			ctx.Abort()
+			return
+		}
+		roles := r.Roles(ctx)
+		for _, perm := range perms {
+			if !r.Allowed(roles, perm) {
+				response.Error(ctx, ecode.ErrPermissionNotAllow)
+				ctx.Abort()
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
+
+// RequireRole 要求当前用户拥有任一 roles 中的角色（包括通过继承获得），需放在 Middleware 之后
+func (r *RBAC) RequireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if Claims(ctx) == nil {
+			response.Error(ctx, ecode.ErrLogin)
+			ctx.Abort()
+			return
+		}
+		if !r.hasRole(r.Roles(ctx), roles) {
+			response.Error(ctx, ecode.ErrPermissionNotAllow)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequirePermission 使用 DefaultRBAC 校验权限
+func RequirePermission(perms ...string) gin.HandlerFunc {
+	return DefaultRBAC.RequirePermission(perms...)
+}
+
+// RequireRole 使用 DefaultRBAC 校验角色
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return DefaultRBAC.RequireRole(roles...)
+}
+
+// role 获取或创建角色，调用方需持有写锁
+func (r *RBAC) role(name string) *rbacRole {
+	role, ok := r.roles[name]
+	if !ok {
+		role = &rbacRole{perms: make(map[string]struct{})}
+		r.roles[name] = role
+	}
+	return role
+}
+
+// allowed 深度优先查找角色及其父角色，visited 用于防止继承成环
+func (r *RBAC) allowed(name, perm string, visited map[string]bool) bool {
+	if visited[name] {
+		return false
+	}
+	visited[name] = true
+	role, ok := r.roles[name]
+	if !ok {
+		return false
+	}
+	for pattern := range role.perms {
+		if matchPermission(pattern, perm) {
+			return true
+		}
+	}
+	for _, parent := range role.parents {
+		if r.allowed(parent, perm, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RBAC) hasRole(owned, required []string) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	var walk func(name string, visited map[string]bool) bool
+	walk = func(name string, visited map[string]bool) bool {
+		if visited[name] {
+			return false
+		}
+		visited[name] = true
+		for _, want := range required {
+			if name == want {
+				return true
+			}
+		}
+		if role, ok := r.roles[name]; ok {
+			for _, parent := range role.parents {
+				if walk(parent, visited) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, name := range owned {
+		if walk(name, make(map[string]bool)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPermission 按 `:` 分段匹配，中间的 `*` 匹配一段，末尾的 `*` 匹配剩余的全部分段
+func matchPermission(pattern, perm string) bool {
+	if pattern == "*" || pattern == perm {
+		return true
+	}
+	ps := strings.Split(pattern, ":")
+	ss := strings.Split(perm, ":")
+	for i, p := range ps {
+		if p == "*" && i == len(ps)-1 {
+			return len(ss) > i
+		}
+		if i >= len(ss) || (p != "*" && p != ss[i]) {
+			return false
+		}
+	}
+	return len(ps) == len(ss)
+}
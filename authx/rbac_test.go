@@ -0,0 +1,40 @@
+package authx
+
+import (
+	"testing"
+)
+
+func TestRBACAllowed(t *testing.T) {
+	r := NewRBAC()
+	r.AddRole("viewer")
+	r.Grant("viewer", "*:read")
+	r.AddRole("operator", "viewer")
+	r.Grant("operator", "order:refund")
+	r.AddRole("admin", "operator", "admin")
+	r.Grant("admin", "user:*")
+	r.Grant("root", "*")
+
+	tests := []struct {
+		name  string
+		roles []string
+		perm  string
+		want  bool
+	}{
+		{name: "direct", roles: []string{"operator"}, perm: "order:refund", want: true},
+		{name: "inherited", roles: []string{"operator"}, perm: "order:read", want: true},
+		{name: "not granted", roles: []string{"viewer"}, perm: "order:refund", want: false},
+		{name: "trailing wildcard", roles: []string{"admin"}, perm: "user:role:update", want: true},
+		{name: "wildcard needs a segment", roles: []string{"admin"}, perm: "user", want: false},
+		{name: "middle wildcard is one segment", roles: []string{"viewer"}, perm: "order:item:read", want: false},
+		{name: "cyclic inheritance", roles: []string{"admin"}, perm: "order:read", want: true},
+		{name: "match all", roles: []string{"root"}, perm: "anything:at:all", want: true},
+		{name: "unknown role", roles: []string{"guest"}, perm: "order:read", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Allowed(tt.roles, tt.perm); got != tt.want {
+				t.Errorf("Allowed() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package authx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused, token family revoked")
+)
+
+// TokenPair 是一组 access token 与 refresh token
+type TokenPair struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`         //单位：秒钟
+	RefreshExpiresIn int64  `json:"refresh_expires_in"` //单位：秒钟
+}
+
+// RefreshRecord 是服务端保存的 refresh token 状态，同一次登录轮换出的 token 属于同一个 Family
+type RefreshRecord struct {
+	ID        string                 `json:"id"`
+	Family    string                 `json:"family"`
+	Kv        map[string]interface{} `json:"kv"`
+	ExpiresAt int64                  `json:"expires_at"`
+}
+
+// RefreshStore 保存 refresh token 的状态
+type RefreshStore interface {
+	// Save 保存新签发的 refresh token
+	Save(ctx context.Context, rec *RefreshRecord, ttl time.Duration) error
+
+	// Consume 原子地将 refresh token 标记为已使用，used 为 true 表示在此之前已经被使用过，
+	// token 不存在时返回 nil
+	Consume(ctx context.Context, id string) (rec *RefreshRecord, used bool, err error)
+
+	// RevokeFamily 吊销整个 token 家族
+	RevokeFamily(ctx context.Context, family string, ttl time.Duration) error
+
+	// FamilyRevoked 判断 token 家族是否已被吊销
+	FamilyRevoked(ctx context.Context, family string) (bool, error)
+}
+
+type RefresherOption func(r *Refresher)
+
+func WithAccessTTL(ttl time.Duration) RefresherOption {
+	return func(r *Refresher) {
+		r.accessTTL = ttl
+	}
+}
+
+func WithRefreshTTL(ttl time.Duration) RefresherOption {
+	return func(r *Refresher) {
+		r.refreshTTL = ttl
+	}
+}
+
+// Refresher 签发 access+refresh token 对，refresh token 只能使用一次，
+// 重放已使用过的 refresh token 会吊销整个家族
+type Refresher struct {
+	auth       Auth
+	store      RefreshStore
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewRefresher(auth Auth, store RefreshStore, opts ...RefresherOption) *Refresher {
+	r := &Refresher{
+		auth:       auth,
+		store:      store,
+		accessTTL:  time.Minute * 30,
+		refreshTTL: time.Hour * 24 * 7,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Issue 登录时签发新的 token 对
+func (r *Refresher) Issue(ctx context.Context, kv map[string]interface{}) (*TokenPair, error) {
+	family, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	return r.issue(ctx, family, kv)
+}
+
+// Refresh 使用 refresh token 换取新的 token 对
+func (r *Refresher) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	rec, used, err := r.store.Consume(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil || rec.ExpiresAt <= time.Now().Unix() {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if used {
+		if err = r.store.RevokeFamily(ctx, rec.Family, r.refreshTTL); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	revoked, err := r.store.FamilyRevoked(ctx, rec.Family)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrRefreshTokenInvalid
+	}
+	return r.issue(ctx, rec.Family, rec.Kv)
+}
+
+// Revoke 吊销 refresh token 所在的家族，用于退出登录
+func (r *Refresher) Revoke(ctx context.Context, refreshToken string) error {
+	rec, _, err := r.store.Consume(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return ErrRefreshTokenInvalid
+	}
+	return r.store.RevokeFamily(ctx, rec.Family, r.refreshTTL)
+}
+
+func (r *Refresher) issue(ctx context.Context, family string, kv map[string]interface{}) (*TokenPair, error) {
+	access, err := r.auth.GenerateToken(copyKv(kv), r.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	id, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	rec := &RefreshRecord{
+		ID:        id,
+		Family:    family,
+		Kv:        copyKv(kv),
+		ExpiresAt: time.Now().Add(r.refreshTTL).Unix(),
+	}
+	if err = r.store.Save(ctx, rec, r.refreshTTL); err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      access,
+		RefreshToken:     id,
+		ExpiresIn:        int64(r.accessTTL / time.Second),
+		RefreshExpiresIn: int64(r.refreshTTL / time.Second),
+	}, nil
+}
+
+// randomToken 生成 256 位的随机不透明 token
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func copyKv(kv map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(kv))
+	for k, v := range kv {
+		c[k] = v
+	}
+	return c
+}
+
+type memoryRefreshEntry struct {
+	rec  *RefreshRecord
+	used bool
+}
+
+// MemoryRefreshStore 是进程内的 RefreshStore，适用于单实例与测试
+type MemoryRefreshStore struct {
+	records  map[string]*memoryRefreshEntry
+	families map[string]int64
+	lock     sync.Mutex
+}
+
+func NewMemoryRefreshStore() *MemoryRefreshStore {
+	return &MemoryRefreshStore{
+		records:  make(map[string]*memoryRefreshEntry),
+		families: make(map[string]int64),
+	}
+}
+
+func (s *MemoryRefreshStore) Save(_ context.Context, rec *RefreshRecord, _ time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.gc()
+	s.records[rec.ID] = &memoryRefreshEntry{rec: rec}
+	return nil
+}
+
+func (s *MemoryRefreshStore) Consume(_ context.Context, id string) (*RefreshRecord, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	entry, ok := s.records[id]
+	if !ok {
+		return nil, false, nil
+	}
+	used := entry.used
+	entry.used = true
+	return entry.rec, used, nil
+}
+
+func (s *MemoryRefreshStore) RevokeFamily(_ context.Context, family string, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.families[family] = time.Now().Add(ttl).Unix()
+	return nil
+}
+
+func (s *MemoryRefreshStore) FamilyRevoked(_ context.Context, family string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	expiresAt, ok := s.families[family]
+	return ok && expiresAt > time.Now().Unix(), nil
+}
+
+// gc 清理过期的记录，调用方需持有锁
+func (s *MemoryRefreshStore) gc() {
+	now := time.Now().Unix()
+	for id, entry := range s.records {
+		if entry.rec.ExpiresAt <= now {
+			delete(s.records, id)
+		}
+	}
+	for family, expiresAt := range s.families {
+		if expiresAt <= now {
+			delete(s.families, family)
+		}
+	}
+}
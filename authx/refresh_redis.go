@@ -0,0 +1,62 @@
+package authx
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const redisRefreshPrefix = "authx:refresh:"
+
+// RedisRefreshStore 基于 redisx.Must 返回的客户端实现 RefreshStore，适用于多实例部署
+type RedisRefreshStore struct {
+	client *redis.Client
+}
+
+func NewRedisRefreshStore(client *redis.Client) *RedisRefreshStore {
+	return &RedisRefreshStore{client: client}
+}
+
+func (s *RedisRefreshStore) Save(ctx context.Context, rec *RefreshRecord, ttl time.Duration) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisRefreshPrefix+"token:"+rec.ID, b, ttl).Err()
+}
+
+func (s *RedisRefreshStore) Consume(ctx context.Context, id string) (*RefreshRecord, bool, error) {
+	key := redisRefreshPrefix + "token:" + id
+	b, err := s.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var rec RefreshRecord
+	if err = json.Unmarshal(b, &rec); err != nil {
+		return nil, false, err
+	}
+	ttl := time.Until(time.Unix(rec.ExpiresAt, 0))
+	if ttl <= 0 {
+		return &rec, false, nil
+	}
+	// SETNX 保证同一个 refresh token 只有一次能成功换取
+	first, err := s.client.SetNX(ctx, redisRefreshPrefix+"used:"+id, 1, ttl).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	return &rec, !first, nil
+}
+
+func (s *RedisRefreshStore) RevokeFamily(ctx context.Context, family string, ttl time.Duration) error {
+	return s.client.Set(ctx, redisRefreshPrefix+"family:"+family, 1, ttl).Err()
+}
+
+func (s *RedisRefreshStore) FamilyRevoked(ctx context.Context, family string) (bool, error) {
+	n, err := s.client.Exists(ctx, redisRefreshPrefix+"family:"+family).Result()
+	return n > 0, err
+}
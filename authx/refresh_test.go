@@ -0,0 +1,49 @@
+package authx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRefresher(t *testing.T) {
+	mr := miniredis.RunT(t)
+	stores := map[string]RefreshStore{
+		"memory": NewMemoryRefreshStore(),
+		"redis":  NewRedisRefreshStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			r := NewRefresher(GetAuth(NameJwt), store)
+
+			first, err := r.Issue(ctx, map[string]interface{}{"uid": "1"})
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+			kv, err := GetAuth(NameJwt).ValidateToken(first.AccessToken)
+			if err != nil || kv["uid"] != "1" {
+				t.Fatalf("ValidateToken() got = %v, error = %v", kv, err)
+			}
+
+			second, err := r.Refresh(ctx, first.RefreshToken)
+			if err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+
+			// 重放已使用的 refresh token 会吊销整个家族
+			if _, err = r.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+				t.Fatalf("Refresh() replay error = %v, want %v", err, ErrRefreshTokenReused)
+			}
+			if _, err = r.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+				t.Fatalf("Refresh() after revoke error = %v, want %v", err, ErrRefreshTokenInvalid)
+			}
+			if _, err = r.Refresh(ctx, "unknown"); !errors.Is(err, ErrRefreshTokenInvalid) {
+				t.Fatalf("Refresh() unknown error = %v, want %v", err, ErrRefreshTokenInvalid)
+			}
+		})
+	}
+}
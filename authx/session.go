@@ -0,0 +1,251 @@
+package authx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExpired  = errors.New("session expired")
+)
+
+// SessionData 是服务端保存的会话
+type SessionData struct {
+	ID        string                 `json:"id"`
+	Subject   string                 `json:"subject"`
+	Kv        map[string]interface{} `json:"kv"`
+	CreatedAt int64                  `json:"created_at"`
+	ExpiresAt int64                  `json:"expires_at"`
+	TTL       int64                  `json:"ttl"` //单位：秒钟，每次访问后顺延
+}
+
+func (d *SessionData) expired() bool {
+	return d.ExpiresAt <= time.Now().Unix()
+}
+
+// SessionStore 保存会话数据
+type SessionStore interface {
+	// Save 保存会话，有效期到 data.ExpiresAt 为止
+	Save(ctx context.Context, data *SessionData) error
+
+	// Get 获取会话，不存在时返回 nil
+	Get(ctx context.Context, id string) (*SessionData, error)
+
+	// Refresh 仅在会话仍存在时将有效期顺延到 data.ExpiresAt，会话已被删除或过期时返回 false，
+	// 不能用 Save 代替，否则与并发的 Delete 交错时会让已退出的会话复活
+	Refresh(ctx context.Context, data *SessionData) (bool, error)
+
+	// Delete 删除会话
+	Delete(ctx context.Context, id string) error
+
+	// List 列出 subject 的全部有效会话
+	List(ctx context.Context, subject string) ([]*SessionData, error)
+}
+
+type SessionOption func(s *Session)
+
+// WithSessionSubjectKey 指定 kv 中表示用户的 key，用于按用户列出与销毁会话，默认为 sub
+func WithSessionSubjectKey(key string) SessionOption {
+	return func(s *Session) {
+		s.subjectKey = key
+	}
+}
+
+// Session 使用随机的不透明 Token，kv 保存在服务端，过期时间随访问滑动顺延
+type Session struct {
+	store      SessionStore
+	subjectKey string
+}
+
+func NewSession(store SessionStore, opts ...SessionOption) *Session {
+	s := &Session{store: store, subjectKey: "sub"}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Session) GenerateToken(kv map[string]interface{}, expired time.Duration) (string, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	data := &SessionData{
+		ID:        id,
+		Kv:        copyKv(kv),
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(expired).Unix(),
+		TTL:       int64(expired / time.Second),
+	}
+	if sub, ok := kv[s.subjectKey]; ok {
+		data.Subject = fmt.Sprint(sub)
+	}
+	if err = s.store.Save(context.Background(), data); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (s *Session) ValidateToken(token string) (map[string]interface{}, error) {
+	ctx := context.Background()
+	data, err := s.store.Get(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, ErrSessionNotFound
+	}
+	if data.expired() {
+		_ = s.store.Delete(ctx, token)
+		return nil, ErrSessionExpired
+	}
+	data.ExpiresAt = time.Now().Unix() + data.TTL
+	ok, err := s.store.Refresh(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return copyKv(data.Kv), nil
+}
+
+// ListSessions 列出用户的全部有效会话
+func (s *Session) ListSessions(ctx context.Context, subject string) ([]*SessionData, error) {
+	return s.store.List(ctx, subject)
+}
+
+// DestroySession 销毁单个会话，用于退出登录
+func (s *Session) DestroySession(ctx context.Context, token string) error {
+	return s.store.Delete(ctx, token)
+}
+
+// DestroySubject 销毁用户的全部会话
+func (s *Session) DestroySubject(ctx context.Context, subject string) error {
+	sessions, err := s.store.List(ctx, subject)
+	if err != nil {
+		return err
+	}
+	for _, data := range sessions {
+		if err = s.store.Delete(ctx, data.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// memoryGCInterval 进程内存储清理过期记录的最小间隔
+const memoryGCInterval = time.Minute
+
+// MemorySessionStore 是进程内的 SessionStore，适用于单实例与测试，写入时顺带清理过期的会话
+type MemorySessionStore struct {
+	sessions map[string]*SessionData
+	subjects map[string]map[string]struct{}
+	gcAt     time.Time
+	lock     sync.Mutex
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]*SessionData),
+		subjects: make(map[string]map[string]struct{}),
+	}
+}
+
+func (s *MemorySessionStore) Save(_ context.Context, data *SessionData) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.gc()
+	c := *data
+	c.Kv = copyKv(data.Kv)
+	s.sessions[data.ID] = &c
+	if data.Subject != "" {
+		if s.subjects[data.Subject] == nil {
+			s.subjects[data.Subject] = make(map[string]struct{})
+		}
+		s.subjects[data.Subject][data.ID] = struct{}{}
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) Get(_ context.Context, id string) (*SessionData, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	data, ok := s.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	c := *data
+	c.Kv = copyKv(data.Kv)
+	return &c, nil
+}
+
+func (s *MemorySessionStore) Refresh(_ context.Context, data *SessionData) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	current, ok := s.sessions[data.ID]
+	if !ok || current.expired() {
+		return false, nil
+	}
+	current.ExpiresAt = data.ExpiresAt
+	return true, nil
+}
+
+func (s *MemorySessionStore) Delete(_ context.Context, id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.delete(id)
+	return nil
+}
+
+func (s *MemorySessionStore) List(_ context.Context, subject string) ([]*SessionData, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var sessions []*SessionData
+	for id := range s.subjects[subject] {
+		data := s.sessions[id]
+		if data.expired() {
+			s.delete(id)
+			continue
+		}
+		c := *data
+		c.Kv = copyKv(data.Kv)
+		sessions = append(sessions, &c)
+	}
+	return sessions, nil
+}
+
+// delete 删除会话及其索引，调用方需持有锁
+func (s *MemorySessionStore) delete(id string) {
+	data, ok := s.sessions[id]
+	if !ok {
+		return
+	}
+	delete(s.sessions, id)
+	if ids, ok := s.subjects[data.Subject]; ok {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(s.subjects, data.Subject)
+		}
+	}
+}
+
+// gc 每隔 memoryGCInterval 清理一次过期的会话，调用方需持有锁
+func (s *MemorySessionStore) gc() {
+	now := time.Now()
+	if now.Sub(s.gcAt) < memoryGCInterval {
+		return
+	}
+	s.gcAt = now
+	for id, data := range s.sessions {
+		if data.expired() {
+			s.delete(id)
+		}
+	}
+}
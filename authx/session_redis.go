@@ -0,0 +1,133 @@
+package authx
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const redisSessionPrefix = "authx:session:"
+
+// RedisSessionStore 基于 redisx.Must 返回的客户端实现 SessionStore，适用于多实例部署
+type RedisSessionStore struct {
+	client *redis.Client
+}
+
+func NewRedisSessionStore(client *redis.Client) *RedisSessionStore {
+	return &RedisSessionStore{client: client}
+}
+
+func (s *RedisSessionStore) Save(ctx context.Context, data *SessionData) error {
+	ttl := time.Until(time.Unix(data.ExpiresAt, 0))
+	if ttl <= 0 {
+		return s.Delete(ctx, data.ID)
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err = s.client.Set(ctx, redisSessionPrefix+"id:"+data.ID, b, ttl).Err(); err != nil {
+		return err
+	}
+	if data.Subject == "" {
+		return nil
+	}
+	if err = s.client.SAdd(ctx, redisSessionPrefix+"sub:"+data.Subject, data.ID).Err(); err != nil {
+		return err
+	}
+	return s.extendIndex(ctx, data.Subject, ttl)
+}
+
+// Refresh 使用 SET XX，会话已被删除或过期时不会重新写入
+func (s *RedisSessionStore) Refresh(ctx context.Context, data *SessionData) (bool, error) {
+	ttl := time.Until(time.Unix(data.ExpiresAt, 0))
+	if ttl <= 0 {
+		return false, s.Delete(ctx, data.ID)
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return false, err
+	}
+	ok, err := s.client.SetXX(ctx, redisSessionPrefix+"id:"+data.ID, b, ttl).Result()
+	if err != nil || !ok || data.Subject == "" {
+		return ok, err
+	}
+	return true, s.extendIndex(ctx, data.Subject, ttl)
+}
+
+// extendIndex 索引的有效期取其中最长的会话
+func (s *RedisSessionStore) extendIndex(ctx context.Context, subject string, ttl time.Duration) error {
+	key := redisSessionPrefix + "sub:" + subject
+	if current, err := s.client.TTL(ctx, key).Result(); err == nil && current < ttl {
+		return s.client.Expire(ctx, key, ttl).Err()
+	}
+	return nil
+}
+
+func (s *RedisSessionStore) Get(ctx context.Context, id string) (*SessionData, error) {
+	b, err := s.client.Get(ctx, redisSessionPrefix+"id:"+id).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var data SessionData
+	if err = json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (s *RedisSessionStore) Delete(ctx context.Context, id string) error {
+	data, err := s.Get(ctx, id)
+	if err != nil || data == nil {
+		return err
+	}
+	if data.Subject != "" {
+		if err = s.client.SRem(ctx, redisSessionPrefix+"sub:"+data.Subject, id).Err(); err != nil {
+			return err
+		}
+	}
+	return s.client.Del(ctx, redisSessionPrefix+"id:"+id).Err()
+}
+
+func (s *RedisSessionStore) List(ctx context.Context, subject string) ([]*SessionData, error) {
+	key := redisSessionPrefix + "sub:" + subject
+	ids, err := s.client.SMembers(ctx, key).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, redisSessionPrefix+"id:"+id)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		sessions []*SessionData
+		missing  []interface{}
+	)
+	for i, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			missing = append(missing, ids[i])
+			continue
+		}
+		var data SessionData
+		if err = json.Unmarshal([]byte(str), &data); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &data)
+	}
+	if len(missing) > 0 {
+		if err = s.client.SRem(ctx, key, missing...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return sessions, nil
+}
@@ -0,0 +1,109 @@
+package authx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestSessionDestroy(t *testing.T) {
+	mr := miniredis.RunT(t)
+	stores := map[string]SessionStore{
+		"memory": NewMemorySessionStore(),
+		"redis":  NewRedisSessionStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewSession(store, WithSessionSubjectKey("uid"))
+
+			var tokens []string
+			for i := 0; i < 2; i++ {
+				token, err := s.GenerateToken(map[string]interface{}{"uid": "1"}, time.Minute)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tokens = append(tokens, token)
+			}
+			sessions, err := s.ListSessions(ctx, "1")
+			if err != nil || len(sessions) != 2 {
+				t.Fatalf("ListSessions() got = %d, error = %v", len(sessions), err)
+			}
+
+			if err = s.DestroySession(ctx, tokens[0]); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = s.ValidateToken(tokens[0]); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("ValidateToken() error = %v, want %v", err, ErrSessionNotFound)
+			}
+			if _, err = s.ValidateToken(tokens[1]); err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+
+			if err = s.DestroySubject(ctx, "1"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = s.ValidateToken(tokens[1]); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("ValidateToken() error = %v, want %v", err, ErrSessionNotFound)
+			}
+		})
+	}
+}
+
+func TestSessionRefreshAfterDelete(t *testing.T) {
+	mr := miniredis.RunT(t)
+	stores := map[string]SessionStore{
+		"memory": NewMemorySessionStore(),
+		"redis":  NewRedisSessionStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewSession(store)
+			token, err := s.GenerateToken(map[string]interface{}{"sub": "1"}, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 模拟 ValidateToken 读取会话后，并发的 DestroySession 先完成
+			data, err := store.Get(ctx, token)
+			if err != nil || data == nil {
+				t.Fatalf("Get() got = %v, error = %v", data, err)
+			}
+			if err = s.DestroySession(ctx, token); err != nil {
+				t.Fatal(err)
+			}
+			data.ExpiresAt = time.Now().Add(time.Hour).Unix()
+			if ok, err := store.Refresh(ctx, data); ok || err != nil {
+				t.Fatalf("Refresh() ok = %v, error = %v", ok, err)
+			}
+			if data, err = store.Get(ctx, token); data != nil || err != nil {
+				t.Fatalf("Get() after Refresh got = %+v, error = %v", data, err)
+			}
+			if sessions, err := s.ListSessions(ctx, "1"); len(sessions) != 0 || err != nil {
+				t.Fatalf("ListSessions() got = %d, error = %v", len(sessions), err)
+			}
+		})
+	}
+}
+
+func TestMemorySessionStoreGC(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySessionStore()
+	past := time.Now().Add(-time.Second).Unix()
+	for _, id := range []string{"a", "b"} {
+		if err := store.Save(ctx, &SessionData{ID: id, Subject: id, ExpiresAt: past}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.gcAt = time.Time{}
+	if err := store.Save(ctx, &SessionData{ID: "c", Subject: "c", ExpiresAt: time.Now().Add(time.Minute).Unix()}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.sessions) != 1 || len(store.subjects) != 1 || store.sessions["c"] == nil {
+		t.Fatalf("gc got sessions = %d, subjects = %d", len(store.sessions), len(store.subjects))
+	}
+}
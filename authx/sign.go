@@ -0,0 +1,266 @@
+package authx
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lascape/gopkg/response"
+	"github.com/lascape/gopkg/response/ecode"
+	"github.com/pkg/errors"
+)
+
+const (
+	HeaderAPIKey    = "X-Api-Key"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+
+	ctxWithAPIKey = "ctx-with-api-key"
+)
+
+var (
+	ErrSignMissing    = errors.New("sign headers missing")
+	ErrSignUnknownKey = errors.New("unknown api key")
+	ErrSignExpired    = errors.New("sign timestamp out of window")
+	ErrSignInvalid    = errors.New("invalid signature")
+	ErrSignNonceUsed  = errors.New("sign nonce already used")
+	ErrSignBodyLarge  = errors.New("sign request body too large")
+)
+
+// CanonicalString 生成待签名的字符串：
+// METHOD\nPATH\n按 key 排序的 query\nhex(sha256(body))\ntimestamp\nnonce
+func CanonicalString(method, path string, query url.Values, body []byte, timestamp, nonce string) string {
+	sum := sha256.Sum256(body)
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		query.Encode(),
+		hex.EncodeToString(sum[:]),
+		timestamp,
+		nonce,
+	}, "\n")
+}
+
+// SignString 使用 HMAC-SHA256 计算签名，结果为 hex 编码
+func SignString(secret, canonical string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(canonical))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SecretProvider 根据 api key 查找签名密钥，key 不存在时返回空字符串
+type SecretProvider interface {
+	Secret(ctx context.Context, apiKey string) (string, error)
+}
+
+type SecretProviderFunc func(ctx context.Context, apiKey string) (string, error)
+
+func (f SecretProviderFunc) Secret(ctx context.Context, apiKey string) (string, error) {
+	return f(ctx, apiKey)
+}
+
+// StaticSecrets 是固定的 api key 与密钥映射
+type StaticSecrets map[string]string
+
+func (s StaticSecrets) Secret(_ context.Context, apiKey string) (string, error) {
+	return s[apiKey], nil
+}
+
+// NonceStore 记录已使用的 nonce，用于防止重放
+type NonceStore interface {
+	// Use 记录 nonce，ttl 内重复使用时返回 false
+	Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore 是进程内的 NonceStore，适用于单实例与测试，写入时顺带清理过期的 nonce
+type MemoryNonceStore struct {
+	nonces map[string]time.Time
+	gcAt   time.Time
+	lock   sync.Mutex
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+func (s *MemoryNonceStore) Use(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.gc()
+	now := time.Now()
+	if expiresAt, ok := s.nonces[nonce]; ok && expiresAt.After(now) {
+		return false, nil
+	}
+	s.nonces[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// gc 每隔 memoryGCInterval 清理一次过期的 nonce，调用方需持有锁
+func (s *MemoryNonceStore) gc() {
+	now := time.Now()
+	if now.Sub(s.gcAt) < memoryGCInterval {
+		return
+	}
+	s.gcAt = now
+	for k, expiresAt := range s.nonces {
+		if !expiresAt.After(now) {
+			delete(s.nonces, k)
+		}
+	}
+}
+
+// Signer 对发出的请求签名，实现了 httpx.Signer：
+//
+//	httpx.New(uri).SetSigner(authx.NewSigner(apiKey, secret)).SetBodyJson(data).Post(ctx)
+type Signer struct {
+	apiKey string
+	secret string
+}
+
+func NewSigner(apiKey, secret string) *Signer {
+	return &Signer{apiKey: apiKey, secret: secret}
+}
+
+func (s *Signer) Sign(req *http.Request, body []byte) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := uuid.NewString()
+	canonical := CanonicalString(req.Method, req.URL.EscapedPath(), req.URL.Query(), body, timestamp, nonce)
+	req.Header.Set(HeaderAPIKey, s.apiKey)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, SignString(s.secret, canonical))
+	return nil
+}
+
+type VerifierOption func(v *Verifier)
+
+// WithSignWindow 设置请求时间戳允许的偏差，默认 5 分钟
+func WithSignWindow(d time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.window = d
+	}
+}
+
+// WithNonceStore 设置 nonce 存储，多实例部署时应使用 RedisNonceStore
+func WithNonceStore(store NonceStore) VerifierOption {
+	return func(v *Verifier) {
+		v.nonces = store
+	}
+}
+
+// WithMaxBody 设置校验签名时最多读取的请求体字节数，超过时返回 ErrSignBodyLarge，默认 10MB
+func WithMaxBody(n int64) VerifierOption {
+	return func(v *Verifier) {
+		v.maxBody = n
+	}
+}
+
+// Verifier 校验 Signer 签名的请求
+type Verifier struct {
+	provider SecretProvider
+	nonces   NonceStore
+	window   time.Duration
+	maxBody  int64
+}
+
+func NewVerifier(provider SecretProvider, opts ...VerifierOption) *Verifier {
+	v := &Verifier{provider: provider, window: time.Minute * 5, maxBody: 10 << 20}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if v.nonces == nil {
+		v.nonces = NewMemoryNonceStore()
+	}
+	return v
+}
+
+// Verify 校验请求签名并返回 api key，请求体读取后会被还原
+func (v *Verifier) Verify(req *http.Request) (string, error) {
+	apiKey := req.Header.Get(HeaderAPIKey)
+	timestamp := req.Header.Get(HeaderTimestamp)
+	nonce := req.Header.Get(HeaderNonce)
+	signature := req.Header.Get(HeaderSignature)
+	if apiKey == "" || timestamp == "" || nonce == "" || signature == "" {
+		return "", ErrSignMissing
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", ErrSignExpired
+	}
+	if d := time.Since(time.Unix(ts, 0)); d > v.window || d < -v.window {
+		return "", ErrSignExpired
+	}
+	secret, err := v.provider.Secret(req.Context(), apiKey)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		return "", ErrSignUnknownKey
+	}
+
+	var body []byte
+	if req.Body != nil {
+		// 签名校验之前不能读取任意长度的请求体，多读 1 字节用于判断是否超过上限
+		if req.ContentLength > v.maxBody {
+			return "", ErrSignBodyLarge
+		}
+		body, err = io.ReadAll(io.LimitReader(req.Body, v.maxBody+1))
+		_ = req.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		if int64(len(body)) > v.maxBody {
+			return "", ErrSignBodyLarge
+		}
+		req.Body = io.NopCloser(bytes.NewBuffer(body))
+	}
+	canonical := CanonicalString(req.Method, req.URL.EscapedPath(), req.URL.Query(), body, timestamp, nonce)
+	if !hmac.Equal([]byte(SignString(secret, canonical)), []byte(strings.ToLower(signature))) {
+		return "", ErrSignInvalid
+	}
+
+	// 签名通过后再记录 nonce，避免伪造的请求占用 nonce
+	ok, err := v.nonces.Use(req.Context(), apiKey+":"+nonce, v.window*2)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrSignNonceUsed
+	}
+	return apiKey, nil
+}
+
+// Middleware 校验签名，失败时返回 ecode.ErrSign，通过后可用 APIKey 读取调用方
+func (v *Verifier) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		apiKey, err := v.Verify(ctx.Request)
+		if err != nil {
+			response.Error(ctx, ecode.Wrap(ecode.ErrSign, err))
+			ctx.Abort()
+			return
+		}
+		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), ctxWithAPIKey, apiKey))
+		ctx.Set(ctxWithAPIKey, apiKey)
+		ctx.Next()
+	}
+}
+
+// APIKey 读取 Verifier.Middleware 校验通过的 api key
+func APIKey(ctx context.Context) string {
+	if v, ok := ctx.Value(ctxWithAPIKey).(string); ok {
+		return v
+	}
+	return ""
+}
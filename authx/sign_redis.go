@@ -0,0 +1,23 @@
+package authx
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const redisNoncePrefix = "authx:nonce:"
+
+// RedisNonceStore 基于 redisx.Must 返回的客户端实现 NonceStore，适用于多实例部署
+type RedisNonceStore struct {
+	client *redis.Client
+}
+
+func NewRedisNonceStore(client *redis.Client) *RedisNonceStore {
+	return &RedisNonceStore{client: client}
+}
+
+func (s *RedisNonceStore) Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, redisNoncePrefix+nonce, 1, ttl).Result()
+}
@@ -0,0 +1,129 @@
+package authx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lascape/gopkg/httpx"
+	"github.com/lascape/gopkg/response/ecode"
+)
+
+func TestSignRequest(t *testing.T) {
+	verifier := NewVerifier(StaticSecrets{"merchant": "secret"})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/orders", verifier.Middleware(), func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"code": 0, "api_key": APIKey(ctx)})
+	})
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	var body struct {
+		Code   int    `json:"code"`
+		APIKey string `json:"api_key"`
+	}
+	err := httpx.New(srv.URL + "/orders?b=2&a=1").
+		SetSigner(NewSigner("merchant", "secret")).
+		SetBodyJson(map[string]interface{}{"amount": 100}).
+		Post(context.Background()).Unmarshal(&body).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != 0 || body.APIKey != "merchant" {
+		t.Fatalf("signed request got = %+v", body)
+	}
+
+	err = httpx.New(srv.URL + "/orders").
+		SetSigner(NewSigner("merchant", "wrong")).
+		SetBodyString("{}").
+		Post(context.Background()).Unmarshal(&body).Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != ecode.ErrSign.Code {
+		t.Fatalf("wrong secret code got = %d, want %d", body.Code, ecode.ErrSign.Code)
+	}
+
+	newRequest := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/orders?x=1", strings.NewReader(body))
+		if err := NewSigner("merchant", "secret").Sign(req, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	req := newRequest(`{"amount":1}`)
+	if _, err = verifier.Verify(req); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if _, err = verifier.Verify(req); !errors.Is(err, ErrSignNonceUsed) {
+		t.Fatalf("Verify() replay error = %v, want %v", err, ErrSignNonceUsed)
+	}
+	req = newRequest(`{"amount":1}`)
+	req.Body = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount":2}`)).Body
+	if _, err = verifier.Verify(req); !errors.Is(err, ErrSignInvalid) {
+		t.Fatalf("Verify() tampered error = %v, want %v", err, ErrSignInvalid)
+	}
+}
+
+func TestVerifyMaxBody(t *testing.T) {
+	verifier := NewVerifier(StaticSecrets{"merchant": "secret"}, WithMaxBody(8))
+	tests := []struct {
+		name    string
+		body    string
+		chunked bool
+		wantErr error
+	}{
+		{name: "at limit", body: "12345678"},
+		{name: "over limit", body: "123456789", wantErr: ErrSignBodyLarge},
+		{name: "unknown length", body: "123456789", chunked: true, wantErr: ErrSignBodyLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			if err := NewSigner("merchant", "secret").Sign(req, []byte(tt.body)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := verifier.Verify(req); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryNonceStore()
+	for _, tt := range []struct {
+		nonce string
+		ttl   time.Duration
+		want  bool
+	}{
+		{nonce: "a", ttl: time.Millisecond, want: true},
+		{nonce: "b", ttl: time.Minute, want: true},
+		{nonce: "b", ttl: time.Minute, want: false},
+	} {
+		if ok, err := store.Use(ctx, tt.nonce, tt.ttl); err != nil || ok != tt.want {
+			t.Fatalf("Use(%s) got = %v, error = %v", tt.nonce, ok, err)
+		}
+	}
+	time.Sleep(time.Millisecond * 5)
+	// 未到清理时间时，过期的 nonce 在查找时按不存在处理
+	if ok, _ := store.Use(ctx, "a", time.Millisecond); !ok || len(store.nonces) != 2 {
+		t.Fatalf("Use(expired) got = %v, size = %d", ok, len(store.nonces))
+	}
+	time.Sleep(time.Millisecond * 5)
+	store.gcAt = time.Time{}
+	if ok, _ := store.Use(ctx, "c", time.Minute); !ok || len(store.nonces) != 2 {
+		t.Fatalf("gc got size = %d, want 2", len(store.nonces))
+	}
+}
@@ -0,0 +1,69 @@
+// envx-kms 用于生成主密钥以及加解密配置文件中 kms:"encode" 字段的值
+//
+//	envx-kms -gen > master.key
+//	envx-kms -key master.key 'my-password'        # 输出 ENC(...)
+//	envx-kms -key master.key -d 'ENC(...)'
+//
+// 未传入值时从标准输入读取一行。
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lascape/gopkg/envx"
+)
+
+func main() {
+	defKeyFile := envx.Declare(envx.Var{
+		Name: "PKG_ENVX_KMS_KEY_FILE", Type: "string", Default: "master.key", Package: "envx",
+		Description: "envx-kms 使用的主密钥文件",
+	}).String()
+	keyFile := flag.String("key", defKeyFile, "master key file")
+	gen := flag.Bool("gen", false, "generate a new master key")
+	decrypt := flag.Bool("d", false, "decrypt instead of encrypt")
+	flag.Parse()
+
+	if err := run(*keyFile, *gen, *decrypt, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(keyFile string, gen, decrypt bool, args []string) error {
+	if gen {
+		key, err := envx.GenerateMasterKey()
+		if err != nil {
+			return err
+		}
+		fmt.Println(key)
+		return nil
+	}
+	kp, err := envx.NewAESKeyProviderFromFile(keyFile)
+	if err != nil {
+		return err
+	}
+	value := strings.Join(args, " ")
+	if len(args) == 0 {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+	var out string
+	if decrypt {
+		out, err = envx.Decrypt(context.Background(), kp, value)
+	} else {
+		out, err = envx.Encrypt(context.Background(), kp, value)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(out)
+	return nil
+}
@@ -0,0 +1,114 @@
+package envx
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Current 返回当前运行环境，读取登记的 PKG_ENV，与 Dump 报告的值一致。优先级为：环境变量或 Watcher 绑定的 PKG_ENV、
+// SetCurrent 设置的值（通常来自 server.Config.Env）、Local。production、development 等常见写法会被归一为 Prod、Dev
+func Current() string {
+	registry.lock.RLock()
+	e := registry.values[envName]
+	registry.lock.RUnlock()
+	if env := normalizeEnv(e.value); env != "" {
+		return env
+	}
+	return Local
+}
+
+// SetCurrent 设置配置中的运行环境，PKG_ENV 来自环境变量或配置文件时仍以其为准，env 为空时恢复默认值
+func SetCurrent(env string) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if e := registry.values[envName]; e.source == SourceEnv || e.source == SourceFile {
+		return
+	}
+	if env = normalizeEnv(env); env == "" {
+		registry.values[envName] = effective{value: Local, source: SourceDefault}
+		return
+	}
+	registry.values[envName] = effective{value: env, source: SourceConfig}
+}
+
+func IsLocal() bool {
+	return Current() == Local
+}
+
+func IsDev() bool {
+	return Current() == Dev
+}
+
+func IsProd() bool {
+	return Current() == Prod
+}
+
+func normalizeEnv(env string) string {
+	switch env = strings.ToLower(strings.TrimSpace(env)); env {
+	case "production", "prd", "release":
+		return Prod
+	case "development", "develop", "test", "testing":
+		return Dev
+	case "":
+		return ""
+	}
+	return env
+}
+
+// Guard 是启动前的安全检查，env 为当前运行环境，不满足要求时返回错误
+type Guard func(env string) error
+
+var guards = struct {
+	m    map[string]Guard
+	lock sync.RWMutex
+}{m: make(map[string]Guard)}
+
+// RegisterGuard 登记安全检查，通常在包的 init 中调用，同名检查以最后一次为准
+func RegisterGuard(name string, g Guard) {
+	guards.lock.Lock()
+	defer guards.lock.Unlock()
+	guards.m[name] = g
+}
+
+// GuardError 列出全部未通过的检查
+type GuardError struct {
+	Env      string
+	Failures []string
+}
+
+func (e *GuardError) Error() string {
+	return "envx: unsafe configuration for env " + e.Env + ": " + strings.Join(e.Failures, "; ")
+}
+
+// Verify 以当前运行环境执行全部安全检查
+func Verify() error {
+	guards.lock.RLock()
+	names := make([]string, 0, len(guards.m))
+	gs := make(map[string]Guard, len(guards.m))
+	for name, g := range guards.m {
+		names = append(names, name)
+		gs[name] = g
+	}
+	guards.lock.RUnlock()
+	sort.Strings(names)
+
+	env := Current()
+	var failures []string
+	for _, name := range names {
+		if err := gs[name](env); err != nil {
+			failures = append(failures, name+": "+err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return &GuardError{Env: env, Failures: failures}
+	}
+	return nil
+}
+
+// MustVerify 执行全部安全检查，未通过时 panic
+func MustVerify() {
+	if err := Verify(); err != nil {
+		panic(err)
+	}
+}
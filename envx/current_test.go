@@ -0,0 +1,108 @@
+package envx
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// setEnv 模拟进程启动时设置了 PKG_ENV，env 为空表示未设置
+func setEnv(t *testing.T, env string) {
+	registry.lock.Lock()
+	old := registry.values[envName]
+	if env == "" {
+		registry.values[envName] = effective{value: Local, source: SourceDefault}
+	} else {
+		registry.values[envName] = effective{value: env, source: SourceEnv}
+	}
+	registry.lock.Unlock()
+	t.Cleanup(func() {
+		registry.lock.Lock()
+		registry.values[envName] = old
+		registry.lock.Unlock()
+	})
+}
+
+func TestCurrent(t *testing.T) {
+	defer SetCurrent("")
+	tests := []struct {
+		name   string
+		env    string
+		config string
+		want   string
+	}{
+		{name: "fallback", want: Local},
+		{name: "config", config: "production", want: Prod},
+		{name: "env wins", env: "dev", config: Prod, want: Dev},
+		{name: "custom", config: "Staging", want: "staging"},
+		{name: "reset", want: Local},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			SetCurrent(tt.config)
+			if got := Current(); got != tt.want {
+				t.Fatalf("Current() got = %s, want %s", got, tt.want)
+			}
+			// Dump 报告的运行环境与 Current 一致
+			for _, s := range Dump() {
+				if s.Name == envName && normalizeEnv(s.Value) != tt.want {
+					t.Fatalf("Dump() PKG_ENV got = %+v, want %s", s, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	setEnv(t, Prod)
+	RegisterGuard("test.b", func(env string) error {
+		if env == Prod {
+			return errors.New("not in prod")
+		}
+		return nil
+	})
+	RegisterGuard("test.a", func(string) error { return errors.New("always") })
+	defer func() {
+		guards.lock.Lock()
+		delete(guards.m, "test.a")
+		delete(guards.m, "test.b")
+		guards.lock.Unlock()
+	}()
+
+	var gerr *GuardError
+	if err := Verify(); !errors.As(err, &gerr) {
+		t.Fatalf("Verify() error = %v, want *GuardError", err)
+	}
+	var failures []string
+	for _, f := range gerr.Failures {
+		if f == "test.a: always" || f == "test.b: not in prod" {
+			failures = append(failures, f)
+		}
+	}
+	if want := []string{"test.a: always", "test.b: not in prod"}; gerr.Env != Prod || !reflect.DeepEqual(failures, want) {
+		t.Fatalf("Verify() got = %+v", gerr)
+	}
+}
+
+func TestCurrentWatched(t *testing.T) {
+	setEnv(t, "")
+	defer SetCurrent("")
+	SetCurrent(Dev)
+	path := writeFile(t, t.TempDir(), "pkg.yaml", "PKG_ENV: production\n")
+	w, err := NewWatcher(path, WithPolling(), WithPollInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.BindString(envName, Local, func(string) {})
+	if got := Current(); got != Prod {
+		t.Fatalf("Current() with watched PKG_ENV got = %s, want %s", got, Prod)
+	}
+	// 文件中的值优先于服务配置
+	SetCurrent(Dev)
+	if got := Current(); got != Prod {
+		t.Fatalf("Current() after SetCurrent got = %s, want %s", got, Prod)
+	}
+}
@@ -1,10 +1,13 @@
 package envx
 
 import (
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 const (
@@ -30,15 +33,218 @@ func (v Value) Bytes() []byte {
 	return []byte(v)
 }
 
+// Bool 支持 strconv.ParseBool 接受的写法，解析失败返回 false
 func (v Value) Bool() bool {
-	return string(v) == "true"
+	b, _ := v.BoolE()
+	return b
+}
+
+func (v Value) BoolE() (bool, error) {
+	return strconv.ParseBool(string(v))
 }
 
 func (v Value) Int() int {
-	i, _ := strconv.Atoi(string(v))
+	i, _ := v.IntE()
+	return i
+}
+
+func (v Value) IntE() (int, error) {
+	return strconv.Atoi(string(v))
+}
+
+func (v Value) Int64() int64 {
+	i, _ := v.Int64E()
 	return i
 }
 
+func (v Value) Int64E() (int64, error) {
+	return strconv.ParseInt(string(v), 10, 64)
+}
+
+func (v Value) Uint() uint64 {
+	u, _ := v.UintE()
+	return u
+}
+
+func (v Value) UintE() (uint64, error) {
+	return strconv.ParseUint(string(v), 10, 64)
+}
+
+func (v Value) Float64() float64 {
+	f, _ := v.Float64E()
+	return f
+}
+
+func (v Value) Float64E() (float64, error) {
+	return strconv.ParseFloat(string(v), 64)
+}
+
+// Duration 支持 "30s"、"5m" 等 time.ParseDuration 格式，纯数字仍按纳秒处理
 func (v Value) Duration() time.Duration {
-	return time.Duration(v.Int())
+	d, _ := v.DurationE()
+	return d
+}
+
+func (v Value) DurationE() (time.Duration, error) {
+	if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+		return time.Duration(i), nil
+	}
+	return time.ParseDuration(string(v))
+}
+
+// Strings 按逗号分隔，去掉空白与空项
+func (v Value) Strings() []string {
+	var items []string
+	for _, item := range strings.Split(string(v), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Map 解析 "k=v,k2=v2" 格式，解析失败返回 nil
+func (v Value) Map() map[string]string {
+	m, _ := v.MapE()
+	return m
+}
+
+func (v Value) MapE() (map[string]string, error) {
+	m := make(map[string]string)
+	for _, item := range v.Strings() {
+		k, val, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, errors.Errorf("invalid map item %q", item)
+		}
+		m[strings.TrimSpace(k)] = strings.TrimSpace(val)
+	}
+	return m, nil
+}
+
+// URL 解析绝对地址，解析失败返回 nil
+func (v Value) URL() *url.URL {
+	u, _ := v.URLE()
+	return u
+}
+
+func (v Value) URLE() (*url.URL, error) {
+	u, err := url.Parse(string(v))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.Errorf("invalid url %q", string(v))
+	}
+	return u, nil
+}
+
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ByteSize 解析 "512"、"10MB"、"1.5GiB" 等格式，单位按 1024 进制，解析失败返回 0
+func (v Value) ByteSize() int64 {
+	n, _ := v.ByteSizeE()
+	return n
+}
+
+func (v Value) ByteSizeE() (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(string(v)))
+	size := int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, size = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.size
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, errors.Errorf("invalid byte size %q", string(v))
+	}
+	return int64(f * float64(size)), nil
+}
+
+// Named 是带环境变量名的 Value，Must 系列方法解析失败时 panic 并带上变量名
+type Named struct {
+	Name string
+	Value
+}
+
+// Lookup 与 ValueByEnv 相同，但保留变量名
+func Lookup(env, def string) Named {
+	return Named{Name: strings.ToUpper(env), Value: ValueByEnv(env, def)}
+}
+
+func (n Named) must(err error) {
+	if err != nil {
+		panic(errors.Wrapf(err, "envx: %s=%q", n.Name, string(n.Value)))
+	}
+}
+
+// MustString 值为空时 panic
+func (n Named) MustString() string {
+	if n.Value == "" {
+		n.must(errors.New("value required"))
+	}
+	return string(n.Value)
+}
+
+func (n Named) MustBool() bool {
+	b, err := n.BoolE()
+	n.must(err)
+	return b
+}
+
+func (n Named) MustInt() int {
+	i, err := n.IntE()
+	n.must(err)
+	return i
+}
+
+func (n Named) MustInt64() int64 {
+	i, err := n.Int64E()
+	n.must(err)
+	return i
+}
+
+func (n Named) MustUint() uint64 {
+	u, err := n.UintE()
+	n.must(err)
+	return u
+}
+
+func (n Named) MustFloat64() float64 {
+	f, err := n.Float64E()
+	n.must(err)
+	return f
+}
+
+func (n Named) MustDuration() time.Duration {
+	d, err := n.DurationE()
+	n.must(err)
+	return d
+}
+
+func (n Named) MustMap() map[string]string {
+	m, err := n.MapE()
+	n.must(err)
+	return m
+}
+
+func (n Named) MustURL() *url.URL {
+	u, err := n.URLE()
+	n.must(err)
+	return u
+}
+
+func (n Named) MustByteSize() int64 {
+	s, err := n.ByteSizeE()
+	n.must(err)
+	return s
 }
@@ -0,0 +1,73 @@
+package envx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		got     func() (interface{}, error)
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "bool 1", got: func() (interface{}, error) { return Value("1").BoolE() }, want: true},
+		{name: "bool TRUE", got: func() (interface{}, error) { return Value("TRUE").BoolE() }, want: true},
+		{name: "bool invalid", got: func() (interface{}, error) { return Value("yes").BoolE() }, want: false, wantErr: true},
+		{name: "int", got: func() (interface{}, error) { return Value("42").IntE() }, want: 42},
+		{name: "int invalid", got: func() (interface{}, error) { return Value("4x").IntE() }, want: 0, wantErr: true},
+		{name: "int64", got: func() (interface{}, error) { return Value("-9000000000").Int64E() }, want: int64(-9000000000)},
+		{name: "uint negative", got: func() (interface{}, error) { return Value("-1").UintE() }, want: uint64(0), wantErr: true},
+		{name: "float", got: func() (interface{}, error) { return Value("0.5").Float64E() }, want: 0.5},
+		{name: "duration", got: func() (interface{}, error) { return Value("5m").DurationE() }, want: time.Minute * 5},
+		{name: "duration nanoseconds", got: func() (interface{}, error) { return Value("30").DurationE() }, want: time.Duration(30)},
+		{name: "duration invalid", got: func() (interface{}, error) { return Value("5 minutes").DurationE() }, want: time.Duration(0), wantErr: true},
+		{name: "map", got: func() (interface{}, error) { return Value("a=1, b = 2").MapE() }, want: map[string]string{"a": "1", "b": "2"}},
+		{name: "map invalid", got: func() (interface{}, error) { return Value("a=1,b").MapE() }, want: map[string]string(nil), wantErr: true},
+		{name: "url", got: func() (interface{}, error) {
+			u, err := Value("https://example.com/a").URLE()
+			if err != nil {
+				return "", err
+			}
+			return u.Host, nil
+		}, want: "example.com"},
+		{name: "url relative", got: func() (interface{}, error) {
+			_, err := Value("/a").URLE()
+			return nil, err
+		}, want: nil, wantErr: true},
+		{name: "byte size", got: func() (interface{}, error) { return Value("512").ByteSizeE() }, want: int64(512)},
+		{name: "byte size MB", got: func() (interface{}, error) { return Value("10MB").ByteSizeE() }, want: int64(10 << 20)},
+		{name: "byte size GiB", got: func() (interface{}, error) { return Value("1.5gib").ByteSizeE() }, want: int64(3 << 29)},
+		{name: "byte size invalid", got: func() (interface{}, error) { return Value("MB").ByteSizeE() }, want: int64(0), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.got()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	if got := Value(" a, ,b ").Strings(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("Strings() got = %v", got)
+	}
+}
+
+func TestNamedMust(t *testing.T) {
+	t.Setenv("PKG_TEST_TIMEOUT", "soon")
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		if !ok || !strings.Contains(err.Error(), "PKG_TEST_TIMEOUT") {
+			t.Fatalf("MustDuration() panic = %v", r)
+		}
+	}()
+	Lookup("PKG_TEST_TIMEOUT", "1s").MustDuration()
+}
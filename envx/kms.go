@@ -0,0 +1,153 @@
+package envx
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	kmsTag    = "kms"
+	kmsEncode = "encode"
+)
+
+var ErrNoKeyProvider = errors.New("envx: encrypted value found but no key provider configured")
+
+// KeyProvider 加解密配置中的密文，可以对接外部 KMS
+type KeyProvider interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// AESKeyProvider 使用本地 32 字节主密钥进行 AES-256-GCM 加解密，密文为 nonce 与 GCM 输出的拼接
+type AESKeyProvider struct {
+	aead cipher.AEAD
+}
+
+func NewAESKeyProvider(key []byte) (*AESKeyProvider, error) {
+	if len(key) != 32 {
+		return nil, errors.New("envx: master key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESKeyProvider{aead: aead}, nil
+}
+
+// NewAESKeyProviderFromFile 从文件读取 base64 编码的主密钥
+func NewAESKeyProviderFromFile(path string) (*AESKeyProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "envx: decode master key %s", path)
+	}
+	return NewAESKeyProvider(key)
+}
+
+// GenerateMasterKey 生成 base64 编码的随机主密钥
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func (p *AESKeyProvider) Encrypt(_ context.Context, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return p.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (p *AESKeyProvider) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < p.aead.NonceSize() {
+		return nil, errors.New("envx: ciphertext too short")
+	}
+	nonce := ciphertext[:p.aead.NonceSize()]
+	return p.aead.Open(nil, nonce, ciphertext[p.aead.NonceSize():], nil)
+}
+
+// IsEncrypted 判断值是否为 ENC(...) 格式的密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, "ENC(") && strings.HasSuffix(value, ")")
+}
+
+// Encrypt 加密明文，返回可直接写入配置文件的 ENC(...) 字符串
+func Encrypt(ctx context.Context, kp KeyProvider, plaintext string) (string, error) {
+	b, err := kp.Encrypt(ctx, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return "ENC(" + base64.StdEncoding.EncodeToString(b) + ")", nil
+}
+
+// Decrypt 解密 ENC(...) 格式的密文，非密文原样返回
+func Decrypt(ctx context.Context, kp KeyProvider, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if kp == nil {
+		return "", ErrNoKeyProvider
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(value, "ENC("), ")"))
+	if err != nil {
+		return "", errors.Wrap(err, "envx: decode secret")
+	}
+	plaintext, err := kp.Decrypt(ctx, b)
+	if err != nil {
+		return "", errors.Wrap(err, "envx: decrypt secret")
+	}
+	return string(plaintext), nil
+}
+
+// DecryptSecrets 遍历 v 指向的结构体（含嵌套结构体、指针与切片），
+// 将带有 kms:"encode" 标签的字符串字段中的 ENC(...) 密文解密
+func DecryptSecrets(ctx context.Context, v interface{}, kp KeyProvider) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("envx: DecryptSecrets requires a pointer to struct")
+	}
+	return decryptStruct(ctx, rv.Elem(), "", kp)
+}
+
+func decryptStruct(ctx context.Context, rv reflect.Value, path string, kp KeyProvider) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		sub := fieldPath(path, f)
+		if f.Tag.Get(kmsTag) == kmsEncode && fv.Kind() == reflect.String {
+			plaintext, err := Decrypt(ctx, kp, fv.String())
+			if err != nil {
+				return errors.Wrap(err, sub)
+			}
+			fv.SetString(plaintext)
+			continue
+		}
+		err := walkStructs(fv, sub, func(v reflect.Value, p string) error { return decryptStruct(ctx, v, p, kp) })
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package envx
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+type testSecretConfig struct {
+	Password   string             `yaml:"password" kms:"encode"`
+	Token      string             `yaml:"token"`
+	ReadConfig []testSecretConfig `yaml:"read_config"`
+}
+
+func TestDecryptSecrets(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	key, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := writeFile(t, dir, "master.key", key+"\n")
+	kp, err := NewAESKeyProviderFromFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := Encrypt(ctx, kp, "p@ss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica, err := Encrypt(ctx, kp, "replica")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeFile(t, dir, "config.yaml", "password: "+secret+"\ntoken: "+secret+"\nread_config:\n  - password: "+replica+"\n  - password: plain\n")
+	var conf testSecretConfig
+	if err = Load(path, &conf, WithEnv(""), WithKeyProvider(kp)); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Password != "p@ss" || conf.ReadConfig[0].Password != "replica" || conf.ReadConfig[1].Password != "plain" {
+		t.Fatalf("Load() got = %+v", conf)
+	}
+	if conf.Token != secret {
+		t.Fatalf("Load() decrypted untagged field: %s", conf.Token)
+	}
+
+	var noKey testSecretConfig
+	if err = Load(path, &noKey, WithEnv("")); !errors.Is(err, ErrNoKeyProvider) {
+		t.Fatalf("Load() without key provider error = %v, want %v", err, ErrNoKeyProvider)
+	}
+
+	other, err := NewAESKeyProvider(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Decrypt(ctx, other, secret); err == nil {
+		t.Fatal("Decrypt() with wrong key should fail")
+	}
+	if _, err = NewAESKeyProviderFromFile(filepath.Join(dir, "missing.key")); err == nil {
+		t.Fatal("NewAESKeyProviderFromFile() missing file should fail")
+	}
+}
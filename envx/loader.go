@@ -0,0 +1,307 @@
+package envx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError 列出全部缺失的必填字段，字段以 yaml 路径表示，如 db.read_config[0].addr
+type ValidationError struct {
+	Missing []string
+}
+
+func (e *ValidationError) Error() string {
+	return "envx: missing required fields: " + strings.Join(e.Missing, ", ")
+}
+
+type loadOptions struct {
+	env    string
+	prefix string
+	kp     KeyProvider
+}
+
+type LoadOption func(o *loadOptions)
+
+// WithEnv 设置环境名，加载 <name>.<env>.yaml 覆盖基础配置，默认为 Current()
+func WithEnv(env string) LoadOption {
+	return func(o *loadOptions) {
+		o.env = env
+	}
+}
+
+// WithEnvPrefix 开启环境变量覆盖并设置前缀，如前缀 APP 时 db.addr 对应 APP_DB_ADDR。
+// 未设置前缀时不读取环境变量，避免 USER、PATH、PORT 等进程变量覆盖同名字段
+func WithEnvPrefix(prefix string) LoadOption {
+	return func(o *loadOptions) {
+		o.prefix = prefix
+	}
+}
+
+// WithKeyProvider 设置解密 kms:"encode" 字段的 KeyProvider
+func WithKeyProvider(kp KeyProvider) LoadOption {
+	return func(o *loadOptions) {
+		o.kp = kp
+	}
+}
+
+// Load 将 YAML 配置加载到 v 中，v 必须是结构体指针。加载顺序为：
+// path 指定的基础文件、同目录下 <name>.<env>.yaml（不存在则跳过）、环境变量（需要 WithEnvPrefix）、default 标签，
+// 然后解密 kms:"encode" 字段中的 ENC(...) 密文，最后校验 required:"true" 的字段，缺失时返回 *ValidationError
+func Load(path string, v interface{}, opts ...LoadOption) error {
+	o := &loadOptions{env: Current()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("envx: Load requires a pointer to struct")
+	}
+	if err := decodeFile(path, v); err != nil {
+		return err
+	}
+	if o.env != "" {
+		ext := filepath.Ext(path)
+		overlay := strings.TrimSuffix(path, ext) + "." + o.env + ext
+		if err := decodeFile(overlay, v); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if o.prefix != "" {
+		if err := overrideEnv(rv.Elem(), []string{o.prefix}); err != nil {
+			return err
+		}
+	}
+	if err := applyDefaults(rv.Elem(), ""); err != nil {
+		return err
+	}
+	if err := DecryptSecrets(context.Background(), v, o.kp); err != nil {
+		return err
+	}
+	var missing []string
+	checkRequired(rv.Elem(), "", &missing)
+	if len(missing) > 0 {
+		return &ValidationError{Missing: missing}
+	}
+	return nil
+}
+
+func decodeFile(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(b, v); err != nil {
+		return errors.Wrapf(err, "envx: decode %s", path)
+	}
+	return nil
+}
+
+// yamlName 返回字段的 yaml 名称，inline 为 true 表示字段内嵌到上一级
+func yamlName(f reflect.StructField) (name string, inline bool) {
+	tag := f.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	for _, p := range parts[1:] {
+		if p == "inline" {
+			return "", true
+		}
+	}
+	if parts[0] != "" {
+		return parts[0], false
+	}
+	return strings.ToLower(f.Name), false
+}
+
+func overrideEnv(rv reflect.Value, path []string) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, inline := yamlName(f)
+		if name == "" && !inline {
+			continue
+		}
+		fv := rv.Field(i)
+		sub := path
+		if !inline {
+			sub = append(append([]string{}, path...), name)
+		}
+		if fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			if err := overrideEnv(fv, sub); err != nil {
+				return err
+			}
+			continue
+		}
+		key := strings.ToUpper(strings.Join(sub, "_"))
+		s, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if err := setString(fv, strings.TrimSpace(s)); err != nil {
+			return errors.Wrapf(err, "envx: env %s", key)
+		}
+	}
+	return nil
+}
+
+func applyDefaults(rv reflect.Value, path string) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		sub := fieldPath(path, f)
+		if def, ok := f.Tag.Lookup("default"); ok && fv.IsZero() {
+			if err := setString(fv, def); err != nil {
+				return errors.Wrapf(err, "envx: default of %s", sub)
+			}
+		}
+		if err := walkStructs(fv, sub, func(v reflect.Value, p string) error { return applyDefaults(v, p) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkRequired(rv reflect.Value, path string, missing *[]string) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		sub := fieldPath(path, f)
+		if f.Tag.Get("required") == "true" && fv.IsZero() {
+			*missing = append(*missing, sub)
+			continue
+		}
+		_ = walkStructs(fv, sub, func(v reflect.Value, p string) error {
+			checkRequired(v, p, missing)
+			return nil
+		})
+	}
+}
+
+// walkStructs 对结构体、结构体指针以及结构体切片中的每个结构体调用 fn
+func walkStructs(fv reflect.Value, path string, fn func(v reflect.Value, path string) error) error {
+	switch fv.Kind() {
+	case reflect.Ptr:
+		if fv.IsNil() {
+			return nil
+		}
+		return walkStructs(fv.Elem(), path, fn)
+	case reflect.Struct:
+		if fv.Type() == reflect.TypeOf(time.Time{}) {
+			return nil
+		}
+		return fn(fv, path)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if err := walkStructs(fv.Index(i), path+"["+strconv.Itoa(i)+"]", fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fieldPath(path string, f reflect.StructField) string {
+	name, inline := yamlName(f)
+	if inline {
+		return path
+	}
+	if name == "" {
+		name = f.Name
+	}
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// setString 将字符串转换为字段类型后赋值，切片使用逗号分隔
+func setString(fv reflect.Value, s string) error {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setString(fv.Elem(), s)
+	}
+	if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := Value(s).DurationE()
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Struct {
+			return errors.Errorf("unsupported type %s", fv.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setString(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+	default:
+		return errors.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
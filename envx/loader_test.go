@@ -0,0 +1,101 @@
+package envx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testDBConfig struct {
+	Addr       string         `yaml:"addr" required:"true"`
+	User       string         `yaml:"user" default:"root"`
+	Timeout    time.Duration  `yaml:"timeout" default:"5s"`
+	ReadConfig []testDBConfig `yaml:"read_config"`
+}
+
+type testConfig struct {
+	ServiceName string       `yaml:"service_name" required:"true"`
+	Debug       bool         `yaml:"debug"`
+	Port        int          `yaml:"port" default:"8080"`
+	Hosts       []string     `yaml:"hosts"`
+	DB          testDBConfig `yaml:"db"`
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.yaml", `
+service_name: demo
+db:
+  addr: 127.0.0.1:3306
+  read_config:
+    - addr: 127.0.0.2:3306
+`)
+	writeFile(t, dir, "config.prod.yaml", `
+debug: true
+db:
+  user: prod
+`)
+	t.Setenv("APP_DB_ADDR", "10.0.0.1:3306")
+	t.Setenv("APP_HOSTS", "a, b")
+
+	var conf testConfig
+	if err := Load(path, &conf, WithEnv(Prod), WithEnvPrefix("APP")); err != nil {
+		t.Fatal(err)
+	}
+	want := testConfig{
+		ServiceName: "demo",
+		Debug:       true,
+		Port:        8080,
+		Hosts:       []string{"a", "b"},
+		DB: testDBConfig{
+			Addr:    "10.0.0.1:3306",
+			User:    "prod",
+			Timeout: time.Second * 5,
+			ReadConfig: []testDBConfig{
+				{Addr: "127.0.0.2:3306", User: "root", Timeout: time.Second * 5},
+			},
+		},
+	}
+	if !reflect.DeepEqual(conf, want) {
+		t.Fatalf("Load() got = %+v, want %+v", conf, want)
+	}
+
+	// 未设置前缀时不读取环境变量
+	t.Setenv("SERVICE_NAME", "other")
+	t.Setenv("PORT", "9090")
+	t.Setenv("DB_USER", "shell-user")
+	var noEnv testConfig
+	if err := Load(path, &noEnv, WithEnv(Local)); err != nil {
+		t.Fatal(err)
+	}
+	if noEnv.ServiceName != "demo" || noEnv.Port != 8080 || noEnv.DB.User != "root" || noEnv.DB.Addr != "127.0.0.1:3306" {
+		t.Fatalf("Load() without prefix got = %+v", noEnv)
+	}
+
+	path = writeFile(t, dir, "missing.yaml", `
+db:
+  read_config:
+    - user: x
+`)
+	var missing testConfig
+	err := Load(path, &missing, WithEnv(Local))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() error = %v, want *ValidationError", err)
+	}
+	wantMissing := []string{"service_name", "db.addr", "db.read_config[0].addr"}
+	if !reflect.DeepEqual(verr.Missing, wantMissing) {
+		t.Fatalf("Load() missing = %v, want %v", verr.Missing, wantMissing)
+	}
+}
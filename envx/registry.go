@@ -0,0 +1,111 @@
+package envx
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const secretMask = "******"
+
+// Var 描述一个 PKG_* 环境变量
+type Var struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Default     string `json:"default"`
+	Description string `json:"description"`
+	Package     string `json:"package"`
+	Secret      bool   `json:"secret"`
+}
+
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceFile    = "file"   // 由 Watcher.Bind 从配置文件应用
+	SourceConfig  = "config" // 由 SetCurrent 从服务配置应用，仅用于 PKG_ENV
+)
+
+// Setting 是变量的生效值，Source 为 default、env、file 或 config
+type Setting struct {
+	Var
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// effective 是变量最近一次交给使用方的值
+type effective struct {
+	value  string
+	source string
+}
+
+var registry = struct {
+	vars   map[string]Var
+	values map[string]effective
+	lock   sync.RWMutex
+}{vars: make(map[string]Var), values: make(map[string]effective)}
+
+// Declare 登记变量并返回其当前值，同名变量重复登记时以最后一次为准
+func Declare(v Var) Named {
+	v.Name = strings.ToUpper(v.Name)
+	n := Lookup(v.Name, v.Default)
+	source := SourceDefault
+	if strings.TrimSpace(os.Getenv(v.Name)) != "" {
+		source = SourceEnv
+	}
+	registry.lock.Lock()
+	registry.vars[v.Name] = v
+	registry.values[v.Name] = effective{value: string(n.Value), source: source}
+	registry.lock.Unlock()
+	return n
+}
+
+// applied 记录已登记变量新的生效值，未登记的变量忽略；默认值不会覆盖 SetCurrent 设置的值
+func applied(name, value, source string) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if _, ok := registry.vars[name]; !ok {
+		return
+	}
+	if source == SourceDefault && registry.values[name].source == SourceConfig {
+		return
+	}
+	registry.values[name] = effective{value: value, source: source}
+}
+
+// Vars 返回已登记的全部变量，按名称排序
+func Vars() []Var {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	vars := make([]Var, 0, len(registry.vars))
+	for _, v := range registry.vars {
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// Dump 返回已登记变量的生效值：Declare 时读取到的值，或之后 Watcher.Bind 应用的值，
+// 不会重新读取环境变量。Secret 变量的值与默认值会被掩码
+func Dump() []Setting {
+	vars := Vars()
+	settings := make([]Setting, 0, len(vars))
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	for _, v := range vars {
+		e := registry.values[v.Name]
+		s := Setting{Var: v, Value: e.value, Source: e.source}
+		if v.Secret {
+			s.Value, s.Default = mask(s.Value), mask(s.Default)
+		}
+		settings = append(settings, s)
+	}
+	return settings
+}
+
+func mask(s string) string {
+	if s == "" {
+		return ""
+	}
+	return secretMask
+}
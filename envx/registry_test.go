@@ -0,0 +1,86 @@
+package envx
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDump(t *testing.T) {
+	t.Setenv("PKG_TEST_SECRET", "s3cret")
+	if got := Declare(Var{Name: "pkg_test_secret", Default: "def", Secret: true}).String(); got != "s3cret" {
+		t.Fatalf("Declare() got = %s", got)
+	}
+	Declare(Var{Name: "PKG_TEST_PLAIN", Default: "plain"})
+	// 登记之后修改的环境变量不会被使用方读取，Dump 也不应报告
+	t.Setenv("PKG_TEST_PLAIN", "changed")
+
+	Declare(Var{Name: "PKG_TEST_WATCHED", Default: "def"})
+	path := writeFile(t, t.TempDir(), "pkg.yaml", "PKG_TEST_WATCHED: from-file\n")
+	w, err := NewWatcher(path, WithPolling(), WithPollInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.BindString("PKG_TEST_WATCHED", "def", func(string) {})
+
+	settings := make(map[string]Setting)
+	for _, s := range Dump() {
+		settings[s.Name] = s
+	}
+	tests := []struct {
+		name   string
+		want   string
+		def    string
+		source string
+	}{
+		{name: "PKG_TEST_SECRET", want: secretMask, def: secretMask, source: "env"},
+		{name: "PKG_TEST_PLAIN", want: "plain", def: "plain", source: "default"},
+		{name: "PKG_TEST_WATCHED", want: "from-file", def: "def", source: "file"},
+		{name: "PKG_HTTPX_BCURL", want: "true", def: "true", source: "default"},
+	}
+	for _, tt := range tests {
+		s, ok := settings[tt.name]
+		if !ok {
+			t.Fatalf("Dump() missing %s", tt.name)
+		}
+		if s.Value != tt.want || s.Default != tt.def || s.Source != tt.source {
+			t.Errorf("Dump() %s got = %+v", tt.name, s)
+		}
+	}
+}
+
+func TestDumpRejectedValue(t *testing.T) {
+	setting := func() Setting {
+		for _, s := range Dump() {
+			if s.Name == "PKG_HTTPX_BCURL" {
+				return s
+			}
+		}
+		t.Fatal("Dump() missing PKG_HTTPX_BCURL")
+		return Setting{}
+	}
+	before := setting()
+	path := writeFile(t, t.TempDir(), "pkg.yaml", "PKG_HTTPX_BCURL: maybe\n")
+	w, err := NewWatcher(path, WithPolling(), WithPollInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	var got []bool
+	w.BindBool("PKG_HTTPX_BCURL", true, func(b bool) { got = append(got, b) })
+	if len(got) != 0 {
+		t.Fatalf("BindBool() accepted %v", got)
+	}
+	if after := setting(); after != before {
+		t.Fatalf("Dump() after rejected value got = %+v, want %+v", after, before)
+	}
+
+	writeFile(t, filepath.Dir(path), "pkg.yaml", "PKG_HTTPX_BCURL: false\n")
+	if _, err = w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if after := setting(); after.Value != "false" || after.Source != SourceFile || len(got) != 1 {
+		t.Fatalf("Dump() after accepted value got = %+v", after)
+	}
+}
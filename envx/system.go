@@ -2,9 +2,28 @@ package envx
 
 import "github.com/sirupsen/logrus"
 
+// envName 是运行环境变量的名称，Current 只从登记的值读取
+const envName = "PKG_ENV"
+
 var (
-	PKG_HTTPX_BCURL          = ValueByEnv("PKG_HTTPX_BCURL", "true").Bool()
-	PKG_HTTPX_ENGRESS        = ValueByEnv("PKG_HTTPX_ENGRESS", "").String()
-	PKG_HTTPX_ENGRESS_IGNORE = ValueByEnv("PKG_HTTPX_ENGRESS_IGNORE", "").String()
-	PKG_LOGX_LEVEL, _        = logrus.ParseLevel(ValueByEnv("PKG_LOGX_LEVEL", "debug").String())
+	PKG_ENV = Declare(Var{
+		Name: envName, Type: "string", Default: Local, Package: "envx",
+		Description: "运行环境 local/dev/prod，通过 Current 读取",
+	}).String()
+	PKG_HTTPX_BCURL = Declare(Var{
+		Name: "PKG_HTTPX_BCURL", Type: "bool", Default: "true", Package: "httpx",
+		Description: "是否为 httpx 请求生成 curl 命令",
+	}).Bool()
+	PKG_HTTPX_ENGRESS = Declare(Var{
+		Name: "PKG_HTTPX_ENGRESS", Type: "[]string", Package: "httpx",
+		Description: "httpx 出口代理列表，逗号分隔，请求时随机选择",
+	}).String()
+	PKG_HTTPX_ENGRESS_IGNORE = Declare(Var{
+		Name: "PKG_HTTPX_ENGRESS_IGNORE", Type: "[]string", Package: "httpx",
+		Description: "不走出口代理的 host，逗号分隔",
+	}).String()
+	PKG_LOGX_LEVEL, _ = logrus.ParseLevel(Declare(Var{
+		Name: "PKG_LOGX_LEVEL", Type: "string", Default: "debug", Package: "logx",
+		Description: "日志级别",
+	}).String())
 )
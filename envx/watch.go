@@ -0,0 +1,340 @@
+package envx
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Change 表示一个配置项的变化，Deleted 为 true 时配置项已从文件中删除
+type Change struct {
+	Key     string
+	Old     Value
+	New     Value
+	Deleted bool
+}
+
+type WatchOption func(w *Watcher)
+
+// WithPollInterval 设置轮询间隔，fsnotify 不可用时使用轮询检测文件变化，默认 5 秒
+func WithPollInterval(d time.Duration) WatchOption {
+	return func(w *Watcher) {
+		w.interval = d
+	}
+}
+
+// WithPolling 强制使用轮询，适用于不支持 inotify 的文件系统，例如部分网络存储
+func WithPolling() WatchOption {
+	return func(w *Watcher) {
+		w.polling = true
+	}
+}
+
+// Watcher 监听一个扁平的 YAML 文件（如 PKG_LOGX_LEVEL: info），文件变化时重新读取，
+// 比较差异并通知订阅者。文件中不存在的配置项回退到环境变量
+type Watcher struct {
+	path     string
+	interval time.Duration
+	polling  bool
+	values   map[string]Value
+	subs     map[string][]func(Change)
+	all      []func([]Change)
+	lock     sync.RWMutex
+	notify   sync.Mutex
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewWatcher 读取 path 并开始监听，使用完毕后调用 Close
+func NewWatcher(path string, opts ...WatchOption) (*Watcher, error) {
+	w := &Watcher{
+		path:     path,
+		interval: time.Second * 5,
+		subs:     make(map[string][]func(Change)),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	values, err := readFlatYAML(path)
+	if err != nil {
+		return nil, err
+	}
+	w.values = values
+	// 在启动 goroutine 前记录文件状态，避免错过启动期间的修改
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if w.polling {
+		go w.poll(fi)
+		return w, nil
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err == nil {
+		// 监听目录而不是文件，以便处理编辑器或 ConfigMap 通过 rename 替换文件的情况
+		err = fw.Add(filepath.Dir(path))
+	}
+	if err != nil {
+		logrus.Warnf("envx: fsnotify unavailable, fallback to polling %s: %v", path, err)
+		if fw != nil {
+			_ = fw.Close()
+		}
+		go w.poll(fi)
+		return w, nil
+	}
+	go w.watch(fw)
+	return w, nil
+}
+
+// Get 返回配置项的当前值，文件中不存在时读取环境变量，都不存在时返回 def
+func (w *Watcher) Get(key, def string) Value {
+	v, _ := w.lookup(key, def)
+	return v
+}
+
+// lookup 返回配置项的当前值及其来源
+func (w *Watcher) lookup(key, def string) (Value, string) {
+	key = strings.ToUpper(key)
+	w.lock.RLock()
+	v, ok := w.values[key]
+	w.lock.RUnlock()
+	if ok {
+		return v, SourceFile
+	}
+	if e := strings.TrimSpace(os.Getenv(key)); e != "" {
+		return Value(e), SourceEnv
+	}
+	return Value(def), SourceDefault
+}
+
+// Subscribe 订阅单个配置项的变化
+func (w *Watcher) Subscribe(key string, fn func(Change)) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	key = strings.ToUpper(key)
+	w.subs[key] = append(w.subs[key], fn)
+}
+
+// OnChange 订阅每次重新读取后的全部变化
+func (w *Watcher) OnChange(fn func([]Change)) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.all = append(w.all, fn)
+}
+
+// Bind 立即以当前值调用 fn，之后每次配置项变化时以新值调用，配置项被删除时回退到环境变量或 def。
+// 绑定的是 Declare 登记的变量时，Dump 会报告最近一次交给 fn 的值
+func (w *Watcher) Bind(key, def string, fn func(Value)) {
+	w.BindE(key, def, func(v Value) error {
+		fn(v)
+		return nil
+	})
+}
+
+// BindE 与 Bind 相同，fn 返回错误表示拒绝该值并保持上一次的值，被拒绝的值不会出现在 Dump 中
+func (w *Watcher) BindE(key, def string, fn func(Value) error) {
+	apply := func() {
+		v, source := w.lookup(key, def)
+		if err := fn(v); err != nil {
+			logrus.Warnf("envx: invalid %s=%q: %v", key, v, err)
+			return
+		}
+		applied(strings.ToUpper(key), string(v), source)
+	}
+	apply()
+	w.Subscribe(key, func(c Change) { apply() })
+}
+
+func (w *Watcher) BindString(key, def string, fn func(string)) {
+	w.Bind(key, def, func(v Value) { fn(v.String()) })
+}
+
+func (w *Watcher) BindStrings(key, def string, fn func([]string)) {
+	w.Bind(key, def, func(v Value) { fn(v.Strings()) })
+}
+
+// BindBool 绑定布尔配置，值无法解析时保持上一次的值
+func (w *Watcher) BindBool(key string, def bool, fn func(bool)) {
+	w.BindE(key, fmt.Sprint(def), func(v Value) error {
+		b, err := v.BoolE()
+		if err != nil {
+			return err
+		}
+		fn(b)
+		return nil
+	})
+}
+
+// BindInt 绑定整数配置，值无法解析时保持上一次的值
+func (w *Watcher) BindInt(key string, def int, fn func(int)) {
+	w.BindE(key, fmt.Sprint(def), func(v Value) error {
+		i, err := v.IntE()
+		if err != nil {
+			return err
+		}
+		fn(i)
+		return nil
+	})
+}
+
+// BindDuration 绑定时长配置，值无法解析时保持上一次的值
+func (w *Watcher) BindDuration(key string, def time.Duration, fn func(time.Duration)) {
+	w.BindE(key, def.String(), func(v Value) error {
+		d, err := v.DurationE()
+		if err != nil {
+			return err
+		}
+		fn(d)
+		return nil
+	})
+}
+
+// Reload 重新读取文件并通知订阅者，返回本次的变化
+func (w *Watcher) Reload() ([]Change, error) {
+	w.notify.Lock()
+	defer w.notify.Unlock()
+	values, err := readFlatYAML(w.path)
+	if err != nil {
+		return nil, err
+	}
+	w.lock.Lock()
+	changes := diffValues(w.values, values)
+	w.values = values
+	all := append([]func([]Change){}, w.all...)
+	subs := make(map[string][]func(Change), len(changes))
+	for _, c := range changes {
+		subs[c.Key] = append([]func(Change){}, w.subs[c.Key]...)
+	}
+	w.lock.Unlock()
+
+	for _, c := range changes {
+		for _, fn := range subs[c.Key] {
+			fn(c)
+		}
+	}
+	if len(changes) > 0 {
+		for _, fn := range all {
+			fn(changes)
+		}
+	}
+	return changes, nil
+}
+
+// Close 停止监听
+func (w *Watcher) Close() {
+	w.once.Do(func() { close(w.done) })
+}
+
+func (w *Watcher) reload() {
+	changes, err := w.Reload()
+	if err != nil {
+		logrus.Warnf("envx: reload %s: %v", w.path, err)
+		return
+	}
+	for _, c := range changes {
+		logrus.Infof("envx: %s changed", c.Key)
+	}
+}
+
+// watch 目录中任意文件变化都会重新读取，由 Reload 比较内容决定是否通知。ConfigMap 挂载时 path 是
+// 指向 ..data/ 的符号链接，更新时只会替换 ..data，不会产生 path 本身的事件
+func (w *Watcher) watch(fw *fsnotify.Watcher) {
+	defer fw.Close()
+	// 一次保存通常会产生多个事件（截断、写入、rename），合并后再读取，避免读到写了一半的文件
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-debounce.C:
+			w.reload()
+		case e, ok := <-fw.Events:
+			if !ok {
+				return
+			}
+			if e.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce.Reset(time.Millisecond * 100)
+			}
+		case err, ok := <-fw.Errors:
+			if !ok {
+				return
+			}
+			logrus.Warnf("envx: watch %s: %v", w.path, err)
+		}
+	}
+}
+
+func (w *Watcher) poll(fi os.FileInfo) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	last, size := fi.ModTime(), fi.Size()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			fi, err := os.Stat(w.path)
+			if err != nil || (fi.ModTime().Equal(last) && fi.Size() == size) {
+				continue
+			}
+			last, size = fi.ModTime(), fi.Size()
+			w.reload()
+		}
+	}
+}
+
+// readFlatYAML 读取扁平的 YAML，键转为大写，列表以逗号拼接
+func readFlatYAML(path string) (map[string]Value, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err = yaml.Unmarshal(b, &raw); err != nil {
+		return nil, errors.Wrapf(err, "envx: decode %s", path)
+	}
+	values := make(map[string]Value, len(raw))
+	for k, v := range raw {
+		switch v := v.(type) {
+		case nil:
+			values[strings.ToUpper(k)] = ""
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[strings.ToUpper(k)] = Value(strings.Join(items, ","))
+		default:
+			values[strings.ToUpper(k)] = Value(fmt.Sprint(v))
+		}
+	}
+	return values, nil
+}
+
+func diffValues(old, new map[string]Value) []Change {
+	var changes []Change
+	for k, v := range new {
+		if o, ok := old[k]; !ok || o != v {
+			changes = append(changes, Change{Key: k, Old: old[k], New: v})
+		}
+	}
+	for k, v := range old {
+		if _, ok := new[k]; !ok {
+			changes = append(changes, Change{Key: k, Old: v, Deleted: true})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
@@ -0,0 +1,119 @@
+package envx
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []WatchOption
+	}{
+		{name: "fsnotify"},
+		{name: "polling", opts: []WatchOption{WithPolling(), WithPollInterval(time.Millisecond * 20)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := writeFile(t, dir, "pkg.yaml", "PKG_LOGX_LEVEL: info\nPKG_HTTPX_BCURL: true\n")
+			w, err := NewWatcher(path, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			levels := make(chan string, 4)
+			w.BindString("PKG_LOGX_LEVEL", "debug", func(s string) { levels <- s })
+			var bcurl []bool
+			w.BindBool("PKG_HTTPX_BCURL", false, func(b bool) { bcurl = append(bcurl, b) })
+			changed := make(chan []Change, 4)
+			w.OnChange(func(c []Change) { changed <- c })
+			if got := <-levels; got != "info" {
+				t.Fatalf("BindString() initial got = %s", got)
+			}
+
+			// 修改时间戳精度较低的文件系统上轮询依赖文件大小变化
+			writeFile(t, dir, "pkg.yaml", "PKG_LOGX_LEVEL: warning\nPKG_HTTPX_ENGRESS: [http://a, http://b]\n")
+			select {
+			case got := <-levels:
+				if got != "warning" {
+					t.Fatalf("BindString() got = %s", got)
+				}
+			case <-time.After(time.Second * 3):
+				t.Fatal("change not delivered")
+			}
+			want := []Change{
+				{Key: "PKG_HTTPX_BCURL", Old: "true", Deleted: true},
+				{Key: "PKG_HTTPX_ENGRESS", New: "http://a,http://b"},
+				{Key: "PKG_LOGX_LEVEL", Old: "info", New: "warning"},
+			}
+			if got := <-changed; !reflect.DeepEqual(got, want) {
+				t.Fatalf("OnChange() got = %+v, want %+v", got, want)
+			}
+			if !reflect.DeepEqual(bcurl, []bool{true, false}) {
+				t.Fatalf("BindBool() got = %v", bcurl)
+			}
+		})
+	}
+}
+
+// TestWatcherConfigMap 模拟 ConfigMap 挂载：pkg.yaml -> ..data/pkg.yaml，..data -> ..v1，
+// 更新时写入新目录并通过 rename 原子替换 ..data
+func TestWatcherConfigMap(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []WatchOption
+	}{
+		{name: "fsnotify"},
+		{name: "polling", opts: []WatchOption{WithPolling(), WithPollInterval(time.Millisecond * 20)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			mount := func(version, content string) {
+				if err := os.Mkdir(filepath.Join(dir, version), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				writeFile(t, filepath.Join(dir, version), "pkg.yaml", content)
+				if err := os.Symlink(version, filepath.Join(dir, "..data_tmp")); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			mount("..v1", "PKG_LOGX_LEVEL: info\n")
+			path := filepath.Join(dir, "pkg.yaml")
+			if err := os.Symlink(filepath.Join("..data", "pkg.yaml"), path); err != nil {
+				t.Fatal(err)
+			}
+			w, err := NewWatcher(path, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			levels := make(chan string, 4)
+			w.BindString("PKG_LOGX_LEVEL", "debug", func(s string) { levels <- s })
+			if got := <-levels; got != "info" {
+				t.Fatalf("BindString() initial got = %s", got)
+			}
+			mount("..v2", "PKG_LOGX_LEVEL: warning\n")
+			if err = os.RemoveAll(filepath.Join(dir, "..v1")); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case got := <-levels:
+				if got != "warning" {
+					t.Fatalf("BindString() got = %s", got)
+				}
+			case <-time.After(time.Second * 3):
+				t.Fatal("symlink swap not delivered")
+			}
+		})
+	}
+}
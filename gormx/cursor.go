@@ -0,0 +1,266 @@
+package gormx
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/lascape/gopkg/envx"
+	"github.com/lascape/gopkg/response/ecode"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorSecret 为空时使用进程内随机密钥，多实例部署或重启后旧游标会失效，生产环境禁止
+var cursorSecret = envx.Declare(envx.Var{
+	Name: "PKG_GORMX_CURSOR_SECRET", Type: "string", Package: "gormx", Secret: true,
+	Description: "游标分页的 HMAC 密钥，多实例部署时需要保持一致，生产环境必须设置",
+}).Bytes()
+
+// cursorSecretRandom PKG_GORMX_CURSOR_SECRET 未设置，使用的是随机密钥
+var cursorSecretRandom bool
+
+func init() {
+	if len(cursorSecret) == 0 {
+		cursorSecret = make([]byte, 32)
+		_, _ = rand.Read(cursorSecret)
+		cursorSecretRandom = true
+	}
+	envx.RegisterGuard("gormx.cursor_secret", guardCursorSecret)
+}
+
+// guardCursorSecret 禁止生产环境使用随机的游标密钥，否则各实例签发的游标互不认可
+func guardCursorSecret(env string) error {
+	if env == envx.Prod && cursorSecretRandom {
+		return errors.New("PKG_GORMX_CURSOR_SECRET must be set in prod")
+	}
+	return nil
+}
+
+// WithCursorSecret 游标签名使用的密钥，默认读取 PKG_GORMX_CURSOR_SECRET
+func WithCursorSecret(secret []byte) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.cursorSecret = secret
+	}
+}
+
+const (
+	cursorNext = "n"
+	cursorPrev = "p"
+)
+
+// CursorPage 游标分页参数。Cursor 为上一次返回的 Next 或 Prev，为空时返回第一页；
+// 携带游标时排序以游标中记录的为准，Sort 仅用于第一页
+type CursorPage struct {
+	Cursor string `json:"cursor" form:"cursor" filter:"-"`
+	Limit  int    `json:"limit" form:"limit" filter:"-"`
+	Sort   string `json:"sort" form:"sort" filter:"-"`
+}
+
+// CursorData 游标分页结果，没有更多数据时对应的游标为空
+type CursorData struct {
+	List interface{} `json:"list"`
+	Next string      `json:"next_cursor"`
+	Prev string      `json:"prev_cursor"`
+}
+
+type cursor struct {
+	Sort   string        `json:"s"`
+	Dir    string        `json:"d"`
+	Values []interface{} `json:"v"`
+}
+
+type sortColumn struct {
+	column string
+	desc   bool
+}
+
+// Cursor 按过滤条件进行游标（keyset）分页，排序字段需在 WithSortable 中登记，
+// 排序末尾会自动追加 id 保证顺序唯一。filter 见 Filter
+func (r *Repository[T]) Cursor(ctx context.Context, filter interface{}, page CursorPage) (CursorData, error) {
+	var (
+		c   cursor
+		err error
+	)
+	if page.Cursor != "" {
+		if c, err = r.decodeCursor(page.Cursor); err != nil {
+			return CursorData{}, err
+		}
+	} else {
+		c = cursor{Sort: page.Sort, Dir: cursorNext}
+	}
+	columns, err := r.sortColumns(c.Sort)
+	if err != nil {
+		return CursorData{}, err
+	}
+	if page.Cursor != "" && len(c.Values) != len(columns) {
+		return CursorData{}, ecode.Wrap(ecode.ErrReqParam, ErrInvalidCursor)
+	}
+
+	tx, err := Filter(r.DB(ctx), filter)
+	if err != nil {
+		return CursorData{}, err
+	}
+	backward := c.Dir == cursorPrev
+	if len(c.Values) > 0 {
+		tx = tx.Where(keysetExpr(columns, c.Values, backward))
+	}
+	for _, col := range columns {
+		tx = tx.Order(clause.OrderByColumn{
+			Column: clause.Column{Table: clause.CurrentTable, Name: col.column},
+			Desc:   col.desc != backward,
+		})
+	}
+	_, limit := Page{PageSize: page.Limit}.limit()
+	list := make([]T, 0, limit+1)
+	if err = tx.Limit(limit + 1).Find(&list).Error; err != nil {
+		return CursorData{}, errors.WithStack(err)
+	}
+	more := len(list) > limit
+	if more {
+		list = list[:limit]
+	}
+	if backward {
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+	}
+
+	data := CursorData{List: list}
+	if len(list) == 0 {
+		return data, nil
+	}
+	// 向后翻页时 more 表示前面还有数据；从游标处翻页时，另一个方向一定还有数据
+	hasNext, hasPrev := more, page.Cursor != ""
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		if data.Next, err = r.encodeCursor(ctx, c.Sort, cursorNext, columns, &list[len(list)-1]); err != nil {
+			return CursorData{}, err
+		}
+	}
+	if hasPrev {
+		if data.Prev, err = r.encodeCursor(ctx, c.Sort, cursorPrev, columns, &list[0]); err != nil {
+			return CursorData{}, err
+		}
+	}
+	return data, nil
+}
+
+func (r *Repository[T]) sortColumns(sort string) ([]sortColumn, error) {
+	if strings.TrimSpace(sort) == "" {
+		sort = r.opts.defaultSort
+	}
+	var columns []sortColumn
+	hasID := false
+	for _, s := range strings.Split(sort, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		desc := strings.HasPrefix(s, "-")
+		s = strings.TrimLeft(s, "+-")
+		column, ok := r.opts.sorts[s]
+		if !ok {
+			return nil, ecode.Wrap(ecode.ErrReqParam, errors.Wrap(ErrInvalidSort, s))
+		}
+		columns = append(columns, sortColumn{column: column, desc: desc})
+		hasID = hasID || column == "id"
+	}
+	if !hasID {
+		desc := len(columns) > 0 && columns[len(columns)-1].desc
+		columns = append(columns, sortColumn{column: "id", desc: desc})
+	}
+	return columns, nil
+}
+
+// keysetExpr 生成 (a > ?) OR (a = ? AND b > ?) ... 形式的条件，倒序或向前翻页时比较方向相反
+func keysetExpr(columns []sortColumn, values []interface{}, backward bool) clause.Expression {
+	ors := make([]clause.Expression, 0, len(columns))
+	for i, col := range columns {
+		ands := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: columns[j].column}, Value: values[j]})
+		}
+		column := clause.Column{Table: clause.CurrentTable, Name: col.column}
+		if col.desc != backward {
+			ands = append(ands, clause.Lt{Column: column, Value: values[i]})
+		} else {
+			ands = append(ands, clause.Gt{Column: column, Value: values[i]})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+	return clause.Or(ors...)
+}
+
+func (r *Repository[T]) encodeCursor(ctx context.Context, sort, dir string, columns []sortColumn, item *T) (string, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(item); err != nil {
+		return "", errors.WithStack(err)
+	}
+	s := stmt.Schema
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		f := s.LookUpField(col.column)
+		if f == nil {
+			return "", errors.Wrapf(ErrInvalidCursor, "unknown column %s", col.column)
+		}
+		values[i], _ = f.ValueOf(ctx, reflect.ValueOf(item).Elem())
+	}
+	payload, err := json.Marshal(cursor{Sort: sort, Dir: dir, Values: values})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(r.signCursor(payload)), nil
+}
+
+func (r *Repository[T]) decodeCursor(s string) (cursor, error) {
+	var c cursor
+	p, sig, ok := strings.Cut(s, ".")
+	if !ok {
+		return c, ecode.Wrap(ecode.ErrReqParam, ErrInvalidCursor)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return c, ecode.Wrap(ecode.ErrReqParam, ErrInvalidCursor)
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, r.signCursor(payload)) {
+		return c, ecode.Wrap(ecode.ErrReqParam, ErrInvalidCursor)
+	}
+	d := json.NewDecoder(bytes.NewReader(payload))
+	d.UseNumber()
+	if err = d.Decode(&c); err != nil || (c.Dir != cursorNext && c.Dir != cursorPrev) {
+		return c, ecode.Wrap(ecode.ErrReqParam, ErrInvalidCursor)
+	}
+	// 整数保持 int64，避免大于 2^53 的 id 丢失精度
+	for i, v := range c.Values {
+		if n, ok := v.(json.Number); ok {
+			if iv, err := n.Int64(); err == nil {
+				c.Values[i] = iv
+			} else if fv, err := n.Float64(); err == nil {
+				c.Values[i] = fv
+			}
+		}
+	}
+	return c, nil
+}
+
+func (r *Repository[T]) signCursor(payload []byte) []byte {
+	secret := r.opts.cursorSecret
+	if len(secret) == 0 {
+		secret = cursorSecret
+	}
+	h := hmac.New(sha256.New, secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
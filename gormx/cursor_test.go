@@ -0,0 +1,134 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lascape/gopkg/envx"
+	"github.com/lascape/gopkg/response/ecode"
+)
+
+func TestRepositoryCursor(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository[testOrder](sqliteDB(t), WithSortable("amount"), WithCursorSecret([]byte("secret")))
+	// amount 有重复值，需要 id 保证顺序唯一
+	for _, amount := range []int64{300, 100, 200, 100, 300, 200, 100} {
+		if err := repo.Create(ctx, &testOrder{Amount: amount, Status: int(amount / 100)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := func(data CursorData) []int64 {
+		var ids []int64
+		for _, o := range data.List.([]testOrder) {
+			ids = append(ids, o.ID)
+		}
+		return ids
+	}
+	equal := func(a, b []int64) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	tests := []struct {
+		name  string
+		sort  string
+		pages [][]int64
+	}{
+		{name: "default sort", pages: [][]int64{{7, 6, 5}, {4, 3, 2}, {1}}},
+		{name: "amount", sort: "amount", pages: [][]int64{{2, 4, 7}, {3, 6, 1}, {5}}},
+		{name: "amount desc", sort: "-amount", pages: [][]int64{{5, 1, 6}, {3, 7, 4}, {2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pages []CursorData
+			page := CursorPage{Limit: 3, Sort: tt.sort}
+			for i, want := range tt.pages {
+				data, err := repo.Cursor(ctx, nil, page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !equal(ids(data), want) {
+					t.Fatalf("page %d got = %v, want %v", i, ids(data), want)
+				}
+				if (data.Prev == "") != (i == 0) || (data.Next == "") != (i == len(tt.pages)-1) {
+					t.Fatalf("page %d cursors got = %+v", i, data)
+				}
+				pages = append(pages, data)
+				page = CursorPage{Cursor: data.Next, Limit: 3}
+			}
+			// 从最后一页向前翻回第一页
+			for i := len(pages) - 1; i > 0; i-- {
+				data, err := repo.Cursor(ctx, nil, CursorPage{Cursor: pages[i].Prev, Limit: 3})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !equal(ids(data), tt.pages[i-1]) {
+					t.Fatalf("prev of page %d got = %v, want %v", i, ids(data), tt.pages[i-1])
+				}
+				if (data.Prev == "") != (i == 1) || data.Next == "" {
+					t.Fatalf("prev of page %d cursors got = %+v", i, data)
+				}
+			}
+		})
+	}
+
+	t.Run("filter", func(t *testing.T) {
+		data, err := repo.Cursor(ctx, testOrderFilter{Status: []int{1}}, CursorPage{Limit: 2, Sort: "amount"})
+		if err != nil || !equal(ids(data), []int64{2, 4}) {
+			t.Fatalf("Cursor() got = %v, error = %v", data, err)
+		}
+		data, err = repo.Cursor(ctx, testOrderFilter{Status: []int{1}}, CursorPage{Cursor: data.Next, Limit: 2})
+		if err != nil || !equal(ids(data), []int64{7}) || data.Next != "" {
+			t.Fatalf("Cursor() got = %v, error = %v", data, err)
+		}
+	})
+
+	first, err := repo.Cursor(ctx, nil, CursorPage{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, page := range map[string]CursorPage{
+		"sort not allowed": {Sort: "status"},
+		"malformed":        {Cursor: "abc"},
+		"tampered":         {Cursor: "e30" + first.Next[len(first.Next)-44:]},
+		"other secret": {Cursor: func() string {
+			other := NewRepository[testOrder](repo.db, WithCursorSecret([]byte("other")))
+			data, _ := other.Cursor(ctx, nil, CursorPage{Limit: 3})
+			return data.Next
+		}()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var e *ecode.ErrorX
+			if _, err := repo.Cursor(ctx, nil, page); !errors.As(err, &e) || e.Errno != ecode.ErrReqParam {
+				t.Fatalf("Cursor() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGuardCursorSecret(t *testing.T) {
+	defer func(random bool) { cursorSecretRandom = random }(cursorSecretRandom)
+	tests := []struct {
+		env     string
+		random  bool
+		wantErr bool
+	}{
+		{env: envx.Dev, random: true},
+		{env: envx.Prod, random: false},
+		{env: envx.Prod, random: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		cursorSecretRandom = tt.random
+		if err := guardCursorSecret(tt.env); (err != nil) != tt.wantErr {
+			t.Fatalf("guardCursorSecret(%s) random = %v, error = %v", tt.env, tt.random, err)
+		}
+	}
+}
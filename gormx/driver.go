@@ -0,0 +1,88 @@
+package gormx
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"github.com/pkg/errors"
+	"gorm.io/driver/clickhouse"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	// DriverClickHouse 不支持事务，ModelPlugin 的软删除会生成 ALTER TABLE ... UPDATE 变更，只适合写入与分析查询
+	DriverClickHouse = "clickhouse"
+)
+
+// Dialector 按 Driver 返回 gorm 的 Dialector
+func (c *Config) Dialector() (gorm.Dialector, error) {
+	switch c.Driver {
+	case "", DriverMySQL:
+		return mysql.Open(c.DSN()), nil
+	case DriverPostgres:
+		return postgres.Open(c.DSN()), nil
+	case DriverSQLite:
+		return sqlite.Open(c.DSN()), nil
+	case DriverClickHouse:
+		return clickhouse.Open(c.DSN()), nil
+	}
+	return nil, errors.Errorf("init gormx err: unsupported driver %q", c.Driver)
+}
+
+// postgresDSN Addr 未指定端口时使用 5432，SslMode 默认 disable
+func (c *Config) postgresDSN() string {
+	host, port, err := net.SplitHostPort(c.Addr)
+	if err != nil {
+		host, port = c.Addr, "5432"
+	}
+	sslMode := c.SslMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s connect_timeout=%d TimeZone=Asia/Shanghai",
+		host, port, pgQuote(c.User), pgQuote(c.Password), pgQuote(c.DbName), sslMode, c.Timeout)
+}
+
+// pgQuote 按 libpq 的 key=value 格式转义，值为空或包含空格、引号时需要用单引号包起来
+func pgQuote(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// sqliteDSN DbName 为数据库文件路径。:memory: 会使用 cache=shared，连接池与读库的每个连接共享同一个内存库，
+// 同一进程中所有 :memory: 配置也是同一个库；最后一个连接关闭后数据丢失，需要保持 MaxIdleConnections > 0
+func (c *Config) sqliteDSN() string {
+	if c.DbName == ":memory:" {
+		return fmt.Sprintf("file::memory:?cache=shared&_pragma=busy_timeout(%d)", c.Timeout*1000)
+	}
+	return fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)", c.DbName, c.Timeout*1000)
+}
+
+// clickhouseDSN 使用 native 协议，Addr 未指定端口时使用 9000
+func (c *Config) clickhouseDSN() string {
+	addr := c.Addr
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "9000")
+	}
+	u := url.URL{
+		Scheme: "clickhouse",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   addr,
+		Path:   "/" + c.DbName,
+		RawQuery: url.Values{
+			"dial_timeout": {fmt.Sprintf("%ds", c.Timeout)},
+			"read_timeout": {fmt.Sprintf("%ds", c.ReadTimeout)},
+		}.Encode(),
+	}
+	return u.String()
+}
@@ -5,20 +5,37 @@ import (
 	"log"
 	"os"
 	"reflect"
+	"sync/atomic"
 	"time"
 
+	"github.com/lascape/gopkg/envx"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 	"gorm.io/plugin/dbresolver"
 )
 
+// debugInstances 使用 WithDebug 创建的实例数量
+var debugInstances atomic.Int32
+
+func init() {
+	envx.RegisterGuard("gormx.debug", guardDebug)
+}
+
+// guardDebug 禁止生产环境使用 WithDebug，它会把每条 SQL 连同参数打印到标准输出
+func guardDebug(env string) error {
+	if env == envx.Prod && debugInstances.Load() > 0 {
+		return errors.New("WithDebug is not allowed in prod, it logs every SQL with its arguments")
+	}
+	return nil
+}
+
 type Options struct {
-	conf  Config
-	debug bool
+	conf    Config
+	debug   bool
+	plugins []gorm.Plugin
 }
 
 type Option func(*Options)
@@ -30,15 +47,23 @@ func WithConfig(conf Config) Option {
 }
 
 // WithDebug 我们现在支持用配置项`debug`来设置在不同环境的debug效果，
-// 同时你可以使用这个方法来强制修改db实例的debug开关
+// 同时你可以使用这个方法来强制修改db实例的debug开关，生产环境下 envx.Verify 会拒绝启动
 func WithDebug() Option {
 	return func(o *Options) {
 		o.debug = true
 	}
 }
 
-// Config mysql的配置字段
+// WithModelPlugin 注册 ModelPlugin，为 Model 提供自动时间戳与软删除
+func WithModelPlugin(opts ...PluginOption) Option {
+	return func(o *Options) {
+		o.plugins = append(o.plugins, NewModelPlugin(opts...))
+	}
+}
+
+// Config 数据库的配置字段，Driver 为空时使用 mysql
 type Config struct {
+	Driver             string   `yaml:"driver"` //mysql、postgres、sqlite、clickhouse，读库未设置时继承写库
 	Source             string   `yaml:"source"` //如果该字段不为空，则直接适用打开该链接
 	Addr               string   `yaml:"addr"`
 	Password           string   `yaml:"password" kms:"encode"`
@@ -60,6 +85,7 @@ func Must(opts ...Option) *gorm.DB {
 	if err != nil {
 		logrus.Panic(err)
 	}
+	setDefaultIfNil(db)
 	return db
 }
 
@@ -69,7 +95,15 @@ func mustDb(opts ...Option) (*gorm.DB, error) {
 		o(k)
 	}
 	k.conf.configInitialize()
-	db, err := gorm.Open(mysql.Open(k.conf.DSN()), &gorm.Config{
+	source, err := k.conf.Dialector()
+	if err != nil {
+		return nil, err
+	}
+	replicas, err := dialector(k.conf.ReadConfig...)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(source, &gorm.Config{
 		NamingStrategy: schema.NamingStrategy{
 			SingularTable: true, // 使用单数表名，启用该选项，此时，`User` 的表名应该是 `t_user`
 		},
@@ -87,39 +121,58 @@ func mustDb(opts ...Option) (*gorm.DB, error) {
 		PrepareStmt:                              false, //执行任何 SQL 时都创建并缓存预编译语句，可以提高后续的调用速度
 	})
 	if err != nil {
-		return nil, errors.Errorf("init mysqlx err: %v", err)
+		return nil, errors.Errorf("init gormx.%s err: %v", source.Name(), err)
 	}
 
 	//注册读写分离
 	err = db.Use(dbresolver.Register(
 		dbresolver.Config{
-			Sources:  dialector(k.conf),
-			Replicas: dialector(k.conf.ReadConfig...),
+			Sources:  []gorm.Dialector{source},
+			Replicas: replicas,
 		}).
 		SetMaxIdleConns(k.conf.MaxIdleConnections).
 		SetMaxOpenConns(k.conf.MaxOpenConnections).
 		SetConnMaxLifetime(time.Duration(k.conf.MaxLifetime) * time.Second))
 	if err != nil {
-		return nil, errors.Errorf("init mysqlx.dbresolver err: %v", err)
+		return nil, errors.Errorf("init gormx.dbresolver err: %v", err)
+	}
+	for _, plugin := range k.plugins {
+		if err = db.Use(plugin); err != nil {
+			return nil, errors.Errorf("init gormx.%s err: %v", plugin.Name(), err)
+		}
+	}
+	if k.debug {
+		debugInstances.Add(1)
 	}
 	return db, nil
 }
 
-func dialector(c ...Config) []gorm.Dialector {
+func dialector(c ...Config) ([]gorm.Dialector, error) {
 	var dialectors []gorm.Dialector
-	for _, config := range c {
-		dialectors = append(dialectors,
-			mysql.Open(config.DSN()),
-		)
+	for i := range c {
+		d, err := c[i].Dialector()
+		if err != nil {
+			return nil, err
+		}
+		dialectors = append(dialectors, d)
 	}
-	return dialectors
+	return dialectors, nil
 }
 
+// DSN 按 Driver 生成连接串，Source 不为空时直接使用 Source
 func (c *Config) DSN() string {
 	if c.Source != "" {
 		return c.Source
 	}
 	c.defaultTimeout()
+	switch c.Driver {
+	case DriverPostgres:
+		return c.postgresDSN()
+	case DriverSQLite:
+		return c.sqliteDSN()
+	case DriverClickHouse:
+		return c.clickhouseDSN()
+	}
 	dsn := fmt.Sprintf(`%s:%s@tcp(%s)/%s?timeout=%ds&readTimeout=%ds&writeTimeout=%ds&charset=utf8mb4,utf8&parseTime=true`,
 		c.User, c.Password, c.Addr, c.DbName, c.Timeout, c.ReadTimeout, c.WriteTimeout) + "&loc=Asia%2FShanghai"
 	return dsn
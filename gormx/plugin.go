@@ -0,0 +1,200 @@
+package gormx
+
+import (
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	columnCreatedAt = "created_at"
+	columnUpdatedAt = "updated_at"
+	columnDeletedAt = "deleted_at"
+
+	// softDeleteEnabled 与 gorm 自带软删除使用同一个标记，gorm 据此判断删除/更新语句是否缺少条件
+	softDeleteEnabled = "soft_delete_enabled"
+)
+
+type PluginOption func(p *ModelPlugin)
+
+// WithMillis 时间戳使用毫秒，默认为秒
+func WithMillis() PluginOption {
+	return func(p *ModelPlugin) {
+		p.millis = true
+	}
+}
+
+// ModelPlugin 为 int64 类型的 created_at/updated_at/deleted_at 字段提供自动时间戳与软删除：
+// 创建与更新时写入 Unix 时间戳，Delete 改为设置 deleted_at，查询与更新自动追加 deleted_at = 0。
+// 使用 db.Use(gormx.NewModelPlugin()) 或 Must(WithModelPlugin()) 注册
+type ModelPlugin struct {
+	millis  bool
+	schemas sync.Map // *schema.Schema -> struct{}
+	lock    sync.Mutex
+}
+
+func NewModelPlugin(opts ...PluginOption) *ModelPlugin {
+	p := &ModelPlugin{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *ModelPlugin) Name() string {
+	return "gormx:model"
+}
+
+func (p *ModelPlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("gormx:timestamp", p.timestamp); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("gormx:timestamp", p.timestamp); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("gormx:soft_delete", p.updateScope); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("gorm:query").Register("gormx:soft_delete", p.queryScope); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("gormx:soft_delete", p.queryScope); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register("gormx:soft_delete", p.softDelete)
+}
+
+func (p *ModelPlugin) now(db *gorm.DB) int64 {
+	if p.millis {
+		return db.NowFunc().UnixMilli()
+	}
+	return db.NowFunc().Unix()
+}
+
+// timestamp 调整整数时间戳字段的精度，由 gorm 在创建与更新时写入。schema 由同一个 db 的全部请求共享，
+// 每个 schema 只在持有锁时修改一次，之后的请求在 Load 命中后才会进入 gorm:create/gorm:update 读取这些字段
+func (p *ModelPlugin) timestamp(db *gorm.DB) {
+	s := db.Statement.Schema
+	if db.Error != nil || s == nil {
+		return
+	}
+	if _, ok := p.schemas.Load(s); ok {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.schemas.Load(s); ok {
+		return
+	}
+	precision := schema.UnixSecond
+	if p.millis {
+		precision = schema.UnixMillisecond
+	}
+	if f := intField(s, columnCreatedAt); f != nil && f.AutoCreateTime != precision {
+		f.AutoCreateTime = precision
+	}
+	if f := intField(s, columnUpdatedAt); f != nil && (f.AutoCreateTime != precision || f.AutoUpdateTime != precision) {
+		f.AutoCreateTime = precision
+		f.AutoUpdateTime = precision
+	}
+	p.schemas.Store(s, struct{}{})
+}
+
+func (p *ModelPlugin) queryScope(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Unscoped || intField(db.Statement.Schema, columnDeletedAt) == nil {
+		return
+	}
+	addSoftDeleteScope(db.Statement)
+}
+
+func (p *ModelPlugin) updateScope(db *gorm.DB) {
+	if db.Statement.SQL.Len() == 0 {
+		p.queryScope(db)
+	}
+}
+
+// softDelete 在 gorm:delete 之前生成 UPDATE 语句，gorm:delete 发现 SQL 已生成时直接执行
+func (p *ModelPlugin) softDelete(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Unscoped || stmt.SQL.Len() > 0 || intField(stmt.Schema, columnDeletedAt) == nil {
+		return
+	}
+	now := p.now(db)
+	stmt.AddClause(clause.Set{{Column: clause.Column{Name: columnDeletedAt}, Value: now}})
+	stmt.SetColumn(columnDeletedAt, now, true)
+
+	_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+	column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+	if len(values) > 0 {
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+	}
+	if stmt.ReflectValue.CanAddr() && stmt.Dest != stmt.Model && stmt.Model != nil {
+		_, queryValues = schema.GetIdentityFieldValuesMap(stmt.Context, reflect.ValueOf(stmt.Model), stmt.Schema.PrimaryFields)
+		column, values = schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+		if len(values) > 0 {
+			stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+		}
+	}
+
+	addSoftDeleteScope(stmt)
+	stmt.AddClauseIfNotExists(clause.Update{})
+	stmt.Build(db.Callback().Update().Clauses...)
+}
+
+func addSoftDeleteScope(stmt *gorm.Statement) {
+	if _, ok := stmt.Clauses[softDeleteEnabled]; ok {
+		return
+	}
+	// 已有的 OR 条件需要先用括号包起来，否则会与 deleted_at = 0 错误结合
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) >= 1 {
+			for _, expr := range where.Exprs {
+				if orCond, ok := expr.(clause.OrConditions); ok && len(orCond.Exprs) == 1 {
+					where.Exprs = []clause.Expression{clause.And(where.Exprs...)}
+					c.Expression = where
+					stmt.Clauses["WHERE"] = c
+					break
+				}
+			}
+		}
+	}
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: columnDeletedAt}, Value: 0},
+	}})
+	stmt.Clauses[softDeleteEnabled] = clause.Clause{}
+}
+
+// intField 返回整数类型的字段，不存在或类型不是整数时返回 nil
+func intField(s *schema.Schema, dbName string) *schema.Field {
+	if s == nil {
+		return nil
+	}
+	f := s.LookUpField(dbName)
+	if f == nil || (f.DataType != schema.Int && f.DataType != schema.Uint) {
+		return nil
+	}
+	return f
+}
+
+// Unscoped 查询时包含已软删除的记录，删除时执行物理删除
+func Unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// Restore 恢复软删除的记录，conds 与 db.Delete 的条件参数相同
+func Restore(db *gorm.DB, value interface{}, conds ...interface{}) *gorm.DB {
+	tx := db.Unscoped().Model(value)
+	if len(conds) > 0 {
+		tx = tx.Where(conds[0], conds[1:]...)
+	}
+	return tx.Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: columnDeletedAt}, Value: 0}).
+		Update(columnDeletedAt, 0)
+}
+
+// ForceDelete 物理删除记录
+func ForceDelete(db *gorm.DB, value interface{}, conds ...interface{}) *gorm.DB {
+	return db.Unscoped().Delete(value, conds...)
+}
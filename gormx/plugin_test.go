@@ -0,0 +1,192 @@
+package gormx
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type testUser struct {
+	Model
+	Name string `gorm:"column:name"`
+}
+
+func dryRunDB(t *testing.T, opts ...PluginOption) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:3306)/test",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		NamingStrategy:         schema.NamingStrategy{SingularTable: true},
+		NowFunc:                func() time.Time { return time.Unix(1700000000, 0) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Use(NewModelPlugin(opts...)); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestModelPlugin(t *testing.T) {
+	db := dryRunDB(t)
+	tests := []struct {
+		name     string
+		run      func(db *gorm.DB) *gorm.DB
+		wantSQL  string
+		wantVars []interface{}
+		wantErr  error
+	}{
+		{
+			name:     "query scope",
+			run:      func(db *gorm.DB) *gorm.DB { return db.Where("name = ?", "a").Find(&[]testUser{}) },
+			wantSQL:  "SELECT * FROM `test_user` WHERE name = ? AND `test_user`.`deleted_at` = ?",
+			wantVars: []interface{}{"a", 0},
+		},
+		{
+			name:     "or conditions are grouped",
+			run:      func(db *gorm.DB) *gorm.DB { return db.Where("name = ?", "a").Or("name = ?", "b").Find(&[]testUser{}) },
+			wantSQL:  "SELECT * FROM `test_user` WHERE (name = ? OR name = ?) AND `test_user`.`deleted_at` = ?",
+			wantVars: []interface{}{"a", "b", 0},
+		},
+		{
+			name:     "count",
+			run:      func(db *gorm.DB) *gorm.DB { var n int64; return db.Model(&testUser{}).Count(&n) },
+			wantSQL:  "SELECT count(*) FROM `test_user` WHERE `test_user`.`deleted_at` = ?",
+			wantVars: []interface{}{0},
+		},
+		{
+			name:     "unscoped",
+			run:      func(db *gorm.DB) *gorm.DB { return Unscoped(db).Find(&[]testUser{}) },
+			wantSQL:  "SELECT * FROM `test_user`",
+			wantVars: nil,
+		},
+		{
+			name:     "soft delete",
+			run:      func(db *gorm.DB) *gorm.DB { return db.Delete(&testUser{Model: Model{ID: 1}}) },
+			wantSQL:  "UPDATE `test_user` SET `deleted_at`=? WHERE `test_user`.`id` = ? AND `test_user`.`deleted_at` = ?",
+			wantVars: []interface{}{int64(1700000000), int64(1), 0},
+		},
+		{
+			name:     "soft delete with conditions",
+			run:      func(db *gorm.DB) *gorm.DB { return db.Delete(&testUser{}, "name = ?", "a") },
+			wantSQL:  "UPDATE `test_user` SET `deleted_at`=? WHERE name = ? AND `test_user`.`deleted_at` = ?",
+			wantVars: []interface{}{int64(1700000000), "a", 0},
+		},
+		{
+			name:    "soft delete without conditions",
+			run:     func(db *gorm.DB) *gorm.DB { return db.Delete(&testUser{}) },
+			wantErr: gorm.ErrMissingWhereClause,
+		},
+		{
+			name:     "force delete",
+			run:      func(db *gorm.DB) *gorm.DB { return ForceDelete(db, &testUser{Model: Model{ID: 1}}) },
+			wantSQL:  "DELETE FROM `test_user` WHERE `test_user`.`id` = ?",
+			wantVars: []interface{}{int64(1)},
+		},
+		{
+			name:     "restore",
+			run:      func(db *gorm.DB) *gorm.DB { return Restore(db, &testUser{Model: Model{ID: 1}}) },
+			wantSQL:  "UPDATE `test_user` SET `deleted_at`=?,`updated_at`=? WHERE `test_user`.`deleted_at` <> ? AND `id` = ?",
+			wantVars: []interface{}{0, int64(1700000000), 0, int64(1)},
+		},
+		{
+			name:     "update scope and timestamp",
+			run:      func(db *gorm.DB) *gorm.DB { return db.Model(&testUser{Model: Model{ID: 1}}).Update("name", "b") },
+			wantSQL:  "UPDATE `test_user` SET `name`=?,`updated_at`=? WHERE `test_user`.`deleted_at` = ? AND `id` = ?",
+			wantVars: []interface{}{"b", int64(1700000000), 0, int64(1)},
+		},
+		{
+			name:     "create timestamp",
+			run:      func(db *gorm.DB) *gorm.DB { return db.Create(&testUser{Name: "a"}) },
+			wantSQL:  "INSERT INTO `test_user` (`created_at`,`updated_at`,`deleted_at`,`name`) VALUES (?,?,?,?)",
+			wantVars: []interface{}{int64(1700000000), int64(1700000000), int64(0), "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := tt.run(db.Session(&gorm.Session{}))
+			if !errors.Is(tx.Error, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", tx.Error, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got := strings.TrimSpace(tx.Statement.SQL.String()); got != tt.wantSQL {
+				t.Fatalf("SQL got = %s\nwant = %s", got, tt.wantSQL)
+			}
+			if !equalVars(tx.Statement.Vars, tt.wantVars) {
+				t.Fatalf("Vars got = %#v, want %#v", tx.Statement.Vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestModelPluginMillis(t *testing.T) {
+	db := dryRunDB(t, WithMillis())
+	tx := db.Create(&testUser{Name: "a"})
+	if tx.Error != nil {
+		t.Fatal(tx.Error)
+	}
+	want := int64(1700000000000)
+	if tx.Statement.Vars[0] != want || tx.Statement.Vars[1] != want {
+		t.Fatalf("Vars got = %#v", tx.Statement.Vars)
+	}
+	tx = db.Delete(&testUser{Model: Model{ID: 1}})
+	if tx.Statement.Vars[0] != want {
+		t.Fatalf("Vars got = %#v", tx.Statement.Vars)
+	}
+}
+
+// TestModelPluginConcurrent 多个 goroutine 同时首次使用同一个 schema，需要配合 go test -race
+func TestModelPluginConcurrent(t *testing.T) {
+	db := dryRunDB(t, WithMillis())
+	want := int64(1700000000000)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			var tx *gorm.DB
+			if i%2 == 0 {
+				tx = db.Create(&testUser{Name: "a"})
+			} else {
+				tx = db.Model(&testUser{Model: Model{ID: 1}}).Updates(testUser{Name: "b"})
+			}
+			if tx.Error != nil {
+				t.Error(tx.Error)
+				return
+			}
+			for _, v := range tx.Statement.Vars {
+				if n, ok := v.(int64); ok && n > 0 && n != want && n != 1 {
+					t.Errorf("Vars got = %#v", tx.Statement.Vars)
+				}
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+}
+
+func equalVars(got, want []interface{}) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
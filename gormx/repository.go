@@ -0,0 +1,371 @@
+package gormx
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/lascape/gopkg/response"
+	"github.com/lascape/gopkg/response/ecode"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 1000
+)
+
+var (
+	ErrInvalidSort   = errors.New("invalid sort field")
+	ErrInvalidFilter = errors.New("invalid filter")
+)
+
+// Page 分页与排序参数，可以直接嵌入到过滤结构体中由 gin 绑定。
+// Sort 为逗号分隔的字段，前缀 - 表示倒序，例如 "-created_at,id"
+type Page struct {
+	Page     int    `json:"page" form:"page" filter:"-"`
+	PageSize int    `json:"page_size" form:"page_size" filter:"-"`
+	Sort     string `json:"sort" form:"sort" filter:"-"`
+}
+
+func (p Page) limit() (offset, limit int) {
+	limit = p.PageSize
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	page := p.Page
+	if page <= 0 {
+		page = 1
+	}
+	return (page - 1) * limit, limit
+}
+
+type repositoryOptions struct {
+	sorts        map[string]string
+	defaultSort  string
+	cursorSecret []byte
+}
+
+type RepositoryOption func(o *repositoryOptions)
+
+// WithSortable 允许排序的字段，字段名即列名；未登记的字段排序时返回 ecode.ErrReqParam
+func WithSortable(fields ...string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		for _, f := range fields {
+			o.sorts[f] = f
+		}
+	}
+}
+
+// WithSortAlias 允许排序的字段，name 为请求中的名称，column 为对应的列
+func WithSortAlias(name, column string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.sorts[name] = column
+	}
+}
+
+// WithDefaultSort 未传入 Sort 时使用的排序，格式与 Page.Sort 相同，默认 "-id"
+func WithDefaultSort(sort string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.defaultSort = sort
+	}
+}
+
+// Repository 基于 *gorm.DB 的通用仓储，T 为模型类型
+type Repository[T any] struct {
+	db   *gorm.DB
+	opts repositoryOptions
+}
+
+func NewRepository[T any](db *gorm.DB, opts ...RepositoryOption) *Repository[T] {
+	o := repositoryOptions{sorts: map[string]string{"id": "id"}, defaultSort: "-id"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Repository[T]{db: db, opts: o}
+}
+
+// DB 返回绑定 ctx 与模型的 *gorm.DB
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	return r.conn(ctx).Model(new(T))
+}
+
+// conn 优先使用 ctx 中由 Transaction 开启的同一实例上的事务
+func (r *Repository[T]) conn(ctx context.Context) *gorm.DB {
+	if tx, ok := txFor(ctx, r.db); ok {
+		return tx.WithContext(ctx)
+	}
+	return r.db.WithContext(ctx)
+}
+
+// Get 按主键查询，记录不存在时返回 gorm.ErrRecordNotFound。
+// id 始终作为参数绑定，不会像 db.First(v, "...") 一样被当作 SQL 条件
+func (r *Repository[T]) Get(ctx context.Context, id interface{}) (*T, error) {
+	v := new(T)
+	if err := r.conn(ctx).Where(primaryEq(id)).First(v).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return v, nil
+}
+
+// First 查询第一条满足过滤条件的记录，filter 见 Filter
+func (r *Repository[T]) First(ctx context.Context, filter interface{}) (*T, error) {
+	tx, err := Filter(r.DB(ctx), filter)
+	if err != nil {
+		return nil, err
+	}
+	v := new(T)
+	if err = tx.First(v).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return v, nil
+}
+
+func (r *Repository[T]) Create(ctx context.Context, v *T) error {
+	return errors.WithStack(r.conn(ctx).Create(v).Error)
+}
+
+// Update 按主键更新 v 中的非零字段，需要更新零值时使用 Updates
+func (r *Repository[T]) Update(ctx context.Context, v *T) error {
+	return errors.WithStack(r.conn(ctx).Model(v).Updates(v).Error)
+}
+
+// Updates 按主键更新指定的列，values 为 map[string]interface{} 或 Dict
+func (r *Repository[T]) Updates(ctx context.Context, id interface{}, values map[string]interface{}) error {
+	return errors.WithStack(r.DB(ctx).Where(primaryEq(id)).Updates(values).Error)
+}
+
+// Delete 按主键删除，注册了 ModelPlugin 时为软删除
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	return errors.WithStack(r.conn(ctx).Where(primaryEq(id)).Delete(new(T)).Error)
+}
+
+func primaryEq(id interface{}) clause.Expression {
+	return clause.Eq{Column: clause.PrimaryColumn, Value: id}
+}
+
+// Find 按过滤条件与排序查询全部记录，不分页
+func (r *Repository[T]) Find(ctx context.Context, filter interface{}, sort string) ([]T, error) {
+	tx, err := Filter(r.DB(ctx), filter)
+	if err != nil {
+		return nil, err
+	}
+	if tx, err = r.order(tx, sort); err != nil {
+		return nil, err
+	}
+	list := make([]T, 0)
+	if err = tx.Find(&list).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return list, nil
+}
+
+// List 按过滤条件分页查询，返回当前页与总数
+func (r *Repository[T]) List(ctx context.Context, filter interface{}, page Page) ([]T, int64, error) {
+	tx, err := Filter(r.DB(ctx), filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	var count int64
+	if err = tx.Session(&gorm.Session{}).Count(&count).Error; err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	list := make([]T, 0)
+	if count == 0 {
+		return list, 0, nil
+	}
+	if tx, err = r.order(tx, page.Sort); err != nil {
+		return nil, 0, err
+	}
+	offset, limit := page.limit()
+	if err = tx.Offset(offset).Limit(limit).Find(&list).Error; err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	return list, count, nil
+}
+
+// Paginate 与 List 相同，结果包装为 response.ListData，可直接传给 response.Success
+func (r *Repository[T]) Paginate(ctx context.Context, filter interface{}, page Page) (response.ListData, error) {
+	list, count, err := r.List(ctx, filter, page)
+	if err != nil {
+		return response.ListData{}, err
+	}
+	return response.ListData{List: list, Count: count}, nil
+}
+
+func (r *Repository[T]) order(tx *gorm.DB, sort string) (*gorm.DB, error) {
+	if strings.TrimSpace(sort) == "" {
+		sort = r.opts.defaultSort
+	}
+	for _, s := range strings.Split(sort, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		desc := strings.HasPrefix(s, "-")
+		s = strings.TrimLeft(s, "+-")
+		column, ok := r.opts.sorts[s]
+		if !ok {
+			return nil, ecode.Wrap(ecode.ErrReqParam, errors.Wrap(ErrInvalidSort, s))
+		}
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Desc: desc})
+	}
+	return tx, nil
+}
+
+// filterField 过滤结构体中的一个字段
+type filterField struct {
+	index  []int
+	column string
+	op     string
+}
+
+var filterCache sync.Map // reflect.Type -> []filterField
+
+// Filter 根据结构体标签为 db 追加查询条件，零值字段（nil 指针、空字符串、空切片等）会被忽略，
+// 需要按零值过滤时使用指针类型。列名取自 form 标签，操作符取自 filter 标签：
+//
+//	Name      string   `form:"name" filter:"like"`         // name LIKE %v%
+//	Status    []int    `form:"status" filter:"in"`         // status IN (...)
+//	CreatedAt []int64  `form:"created_at" filter:"range"`  // created_at >= v[0] AND created_at <= v[1]
+//	MinAmount *int64   `form:"min_amount" filter:"gte,amount"`
+//
+// 支持 eq（默认）、ne、like、in、gt、gte、lt、lte、range，逗号后可指定列名，filter:"-" 表示忽略该字段。
+// filter 为 nil 时不追加条件
+func Filter(db *gorm.DB, filter interface{}) (*gorm.DB, error) {
+	if filter == nil {
+		return db, nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(filter))
+	if !v.IsValid() {
+		return db, nil
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, ecode.Wrap(ecode.ErrReqParam, errors.Wrapf(ErrInvalidFilter, "%T is not a struct", filter))
+	}
+	fields, err := filterFields(v.Type())
+	if err != nil {
+		return nil, ecode.Wrap(ecode.ErrReqParam, err)
+	}
+	for _, f := range fields {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || fv.IsZero() {
+			continue
+		}
+		fv = reflect.Indirect(fv)
+		column := clause.Column{Table: clause.CurrentTable, Name: f.column}
+		switch f.op {
+		case "eq":
+			db = db.Where(clause.Eq{Column: column, Value: fv.Interface()})
+		case "ne":
+			db = db.Where(clause.Neq{Column: column, Value: fv.Interface()})
+		case "like":
+			// 显式指定转义符，sqlite 的 LIKE 没有默认转义符
+			db = db.Where(clause.Expr{SQL: "? LIKE ? ESCAPE '!'", Vars: []interface{}{column, "%" + escapeLike(fv.String()) + "%"}})
+		case "gt":
+			db = db.Where(clause.Gt{Column: column, Value: fv.Interface()})
+		case "gte":
+			db = db.Where(clause.Gte{Column: column, Value: fv.Interface()})
+		case "lt":
+			db = db.Where(clause.Lt{Column: column, Value: fv.Interface()})
+		case "lte":
+			db = db.Where(clause.Lte{Column: column, Value: fv.Interface()})
+		case "in":
+			values := make([]interface{}, fv.Len())
+			for i := range values {
+				values[i] = fv.Index(i).Interface()
+			}
+			db = db.Where(clause.IN{Column: column, Values: values})
+		case "range":
+			if fv.Len() > 0 && !fv.Index(0).IsZero() {
+				db = db.Where(clause.Gte{Column: column, Value: fv.Index(0).Interface()})
+			}
+			if fv.Len() > 1 && !fv.Index(1).IsZero() {
+				db = db.Where(clause.Lte{Column: column, Value: fv.Index(1).Interface()})
+			}
+		}
+	}
+	return db, nil
+}
+
+func filterFields(t reflect.Type) ([]filterField, error) {
+	if fields, ok := filterCache.Load(t); ok {
+		return fields.([]filterField), nil
+	}
+	var fields []filterField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("filter")
+		if tag == "-" || !sf.IsExported() {
+			continue
+		}
+		form := strings.Split(sf.Tag.Get("form"), ",")[0]
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		// 嵌入的结构体（如 Page）展开处理
+		if sf.Anonymous && ft.Kind() == reflect.Struct && !hasTag && form == "" {
+			sub, err := filterFields(ft)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range sub {
+				f.index = append([]int{i}, f.index...)
+				fields = append(fields, f)
+			}
+			continue
+		}
+		op, column, _ := strings.Cut(tag, ",")
+		if op == "" {
+			op = "eq"
+		}
+		if column == "" {
+			column = form
+		}
+		if column == "" || form == "-" {
+			continue
+		}
+		switch op {
+		case "eq", "ne", "gt", "gte", "lt", "lte":
+		case "like":
+			if ft.Kind() != reflect.String {
+				return nil, errors.Wrapf(ErrInvalidFilter, "%s: like requires a string", sf.Name)
+			}
+		case "in", "range":
+			if ft.Kind() != reflect.Slice && ft.Kind() != reflect.Array {
+				return nil, errors.Wrapf(ErrInvalidFilter, "%s: %s requires a slice", sf.Name, op)
+			}
+		default:
+			return nil, errors.Wrapf(ErrInvalidFilter, "%s: unknown operator %s", sf.Name, op)
+		}
+		fields = append(fields, filterField{index: []int{i}, column: column, op: op})
+	}
+	filterCache.Store(t, fields)
+	return fields, nil
+}
+
+// fieldByIndex 与 reflect.Value.FieldByIndex 相同，遇到 nil 的嵌入指针时返回 false
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(s)
+}
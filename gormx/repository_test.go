@@ -0,0 +1,170 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/lascape/gopkg/response/ecode"
+	"gorm.io/gorm"
+)
+
+type testOrder struct {
+	Model
+	No     string `gorm:"column:no" form:"no"`
+	Status int    `gorm:"column:status" form:"status"`
+	Amount int64  `gorm:"column:amount" form:"amount"`
+}
+
+type testOrderFilter struct {
+	Page
+	No        string  `form:"no" filter:"like"`
+	Status    []int   `form:"status" filter:"in"`
+	CreatedAt []int64 `form:"created_at" filter:"range"`
+	MinAmount *int64  `form:"min_amount" filter:"gte,amount"`
+	Ignored   string  `form:"ignored" filter:"-"`
+}
+
+func sqliteDB(t *testing.T) *gorm.DB {
+	db, err := mustDb(WithConfig(Config{
+		Driver: DriverSQLite,
+		DbName: filepath.Join(t.TempDir(), "app.db"),
+	}), WithModelPlugin())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&testOrder{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository[testOrder](sqliteDB(t), WithSortable("amount", "created_at"), WithSortAlias("order_no", "no"))
+	for i, no := range []string{"A100", "A200", "B100", "B_200", "C100"} {
+		o := &testOrder{No: no, Status: i % 3, Amount: int64(i+1) * 100}
+		if err := repo.Create(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.DB(ctx).Where("id = ?", o.ID).UpdateColumn("created_at", int64(i+1)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	zero := int64(0)
+	min := int64(300)
+	tests := []struct {
+		name      string
+		filter    interface{}
+		page      Page
+		wantNos   []string
+		wantCount int64
+		wantErr   *ecode.Errno
+	}{
+		{name: "default sort", filter: nil, page: Page{PageSize: 2}, wantNos: []string{"C100", "B_200"}, wantCount: 5},
+		{name: "second page", filter: &testOrderFilter{}, page: Page{Page: 2, PageSize: 2}, wantNos: []string{"B100", "A200"}, wantCount: 5},
+		{name: "like", filter: testOrderFilter{No: "100"}, page: Page{Sort: "amount"}, wantNos: []string{"A100", "B100", "C100"}, wantCount: 3},
+		{name: "like escapes wildcards", filter: testOrderFilter{No: "_"}, wantNos: []string{"B_200"}, wantCount: 1},
+		{name: "in", filter: testOrderFilter{Status: []int{0, 2}}, page: Page{Sort: "order_no"}, wantNos: []string{"A100", "B100", "B_200"}, wantCount: 3},
+		{name: "range", filter: testOrderFilter{CreatedAt: []int64{2, 4}}, page: Page{Sort: "-created_at"}, wantNos: []string{"B_200", "B100", "A200"}, wantCount: 3},
+		{name: "open range", filter: testOrderFilter{CreatedAt: []int64{0, 1}}, wantNos: []string{"A100"}, wantCount: 1},
+		{name: "pointer", filter: testOrderFilter{MinAmount: &min}, page: Page{Sort: "amount"}, wantNos: []string{"B100", "B_200", "C100"}, wantCount: 3},
+		{name: "zero pointer", filter: testOrderFilter{MinAmount: &zero, Ignored: "x"}, page: Page{PageSize: 1}, wantNos: []string{"C100"}, wantCount: 5},
+		{name: "no match", filter: testOrderFilter{No: "Z"}, wantNos: []string{}, wantCount: 0},
+		{name: "sort not allowed", filter: nil, page: Page{Sort: "status"}, wantErr: ecode.ErrReqParam},
+		{name: "not a struct", filter: "no", wantErr: ecode.ErrReqParam},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := repo.Paginate(ctx, tt.filter, tt.page)
+			if tt.wantErr != nil {
+				var e *ecode.ErrorX
+				if !errors.As(err, &e) || e.Errno != tt.wantErr {
+					t.Fatalf("Paginate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			list := data.List.([]testOrder)
+			if data.Count != tt.wantCount || len(list) != len(tt.wantNos) {
+				t.Fatalf("Paginate() got = %+v", data)
+			}
+			for i := range list {
+				if list[i].No != tt.wantNos[i] {
+					t.Fatalf("Paginate() got[%d] = %s, want %s", i, list[i].No, tt.wantNos[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository[testOrder](sqliteDB(t))
+	o := &testOrder{No: "A100", Amount: 100}
+	if err := repo.Create(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	o.Amount = 200
+	if err := repo.Update(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Updates(ctx, o.ID, Dict{"status": 1}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.Get(ctx, o.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Amount != 200 || got.Status != 1 {
+		t.Fatalf("Get() got = %+v", got)
+	}
+	if got, err = repo.First(ctx, testOrderFilter{No: "A1"}); err != nil || got.ID != o.ID {
+		t.Fatalf("First() got = %+v, error = %v", got, err)
+	}
+	if err = repo.Delete(ctx, o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = repo.Get(ctx, o.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Get() after Delete error = %v", err)
+	}
+	list, err := repo.Find(ctx, nil, "")
+	if err != nil || len(list) != 0 {
+		t.Fatalf("Find() got = %+v, error = %v", list, err)
+	}
+}
+
+func TestRepositoryStringID(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository[testOrder](sqliteDB(t))
+	for _, no := range []string{"A100", "A200"} {
+		if err := repo.Create(ctx, &testOrder{No: no}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 与 c.Param("id") 一样传入字符串，只能作为主键值绑定
+	if got, err := repo.Get(ctx, "2"); err != nil || got.No != "A200" {
+		t.Fatalf("Get(\"2\") got = %+v, error = %v", got, err)
+	}
+	for _, id := range []string{"1=1", "1 OR 1=1", "id > 0"} {
+		if _, err := repo.Get(ctx, id); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Get(%q) error = %v", id, err)
+		}
+		if err := repo.Delete(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if list, err := repo.Find(ctx, nil, ""); err != nil || len(list) != 2 {
+			t.Fatalf("Delete(%q) left %d rows, error = %v", id, len(list), err)
+		}
+	}
+	if err := repo.Delete(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := repo.Find(ctx, nil, ""); err != nil || len(list) != 1 || list[0].No != "A200" {
+		t.Fatalf("Delete(\"1\") got = %+v, error = %v", list, err)
+	}
+}
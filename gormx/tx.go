@@ -0,0 +1,170 @@
+package gormx
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	mysqlDeadlock        = 1213
+	mysqlLockWaitTimeout = 1205
+)
+
+var defaultDB = struct {
+	db   *gorm.DB
+	lock sync.RWMutex
+}{}
+
+// SetDefault 设置 Transaction 与 DB 使用的默认实例，第一个通过 Must 创建的实例会自动成为默认实例
+func SetDefault(db *gorm.DB) {
+	defaultDB.lock.Lock()
+	defer defaultDB.lock.Unlock()
+	defaultDB.db = db
+}
+
+func setDefaultIfNil(db *gorm.DB) {
+	defaultDB.lock.Lock()
+	defer defaultDB.lock.Unlock()
+	if defaultDB.db == nil {
+		defaultDB.db = db
+	}
+}
+
+func getDefault() *gorm.DB {
+	defaultDB.lock.RLock()
+	defer defaultDB.lock.RUnlock()
+	return defaultDB.db
+}
+
+type txKey struct{}
+
+// txChain 保存 ctx 中的事务，在另一个实例上开启的事务不会覆盖外层实例的事务
+type txChain struct {
+	tx     *gorm.DB
+	parent *txChain
+}
+
+// FromContext 返回 ctx 中由 Transaction 开启的最内层事务
+func FromContext(ctx context.Context) (*gorm.DB, bool) {
+	c, ok := ctx.Value(txKey{}).(*txChain)
+	if !ok {
+		return nil, false
+	}
+	return c.tx, true
+}
+
+// txFor 返回 ctx 中在 db 同一实例上开启的事务
+func txFor(ctx context.Context, db *gorm.DB) (*gorm.DB, bool) {
+	c, _ := ctx.Value(txKey{}).(*txChain)
+	for ; c != nil; c = c.parent {
+		if c.tx.Dialector == db.Dialector {
+			return c.tx, true
+		}
+	}
+	return nil, false
+}
+
+// DB 返回 ctx 中默认实例上的事务，不在事务中时返回默认实例，均已绑定 ctx
+func DB(ctx context.Context) *gorm.DB {
+	db := getDefault()
+	if db == nil {
+		logrus.Panic("gormx: default db is not set, call gormx.SetDefault or gormx.Must first")
+	}
+	if tx, ok := txFor(ctx, db); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+type txOptions struct {
+	db      *gorm.DB
+	retries int
+	backoff time.Duration
+	sqlOpts *sql.TxOptions
+}
+
+type TxOption func(o *txOptions)
+
+// WithTxDB 在该实例上开启事务，默认使用 SetDefault 设置的实例；ctx 中已有其他实例的事务时开启独立的事务
+func WithTxDB(db *gorm.DB) TxOption {
+	return func(o *txOptions) {
+		o.db = db
+	}
+}
+
+// WithTxRetry 遇到 MySQL 死锁（1213）或锁等待超时（1205）时整个事务最多重试 n 次，默认 2 次，0 表示不重试
+func WithTxRetry(n int) TxOption {
+	return func(o *txOptions) {
+		o.retries = n
+	}
+}
+
+// WithTxBackoff 重试前的基础等待时间，第 n 次重试等待 n*d 加上随机抖动，默认 50ms
+func WithTxBackoff(d time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.backoff = d
+	}
+}
+
+// WithTxOptions 设置隔离级别、只读等事务参数
+func WithTxOptions(opts *sql.TxOptions) TxOption {
+	return func(o *txOptions) {
+		o.sqlOpts = opts
+	}
+}
+
+// Transaction 在事务中执行 fn，fn 返回错误或 panic 时回滚。事务保存在传给 fn 的 ctx 中，
+// 通过 DB(ctx) 或 Repository 获取。ctx 中已有同一实例的事务时使用保存点（SAVEPOINT），
+// 内层回滚不影响外层；其他实例的事务互相独立，提交与回滚各自进行。
+// 只有最外层事务会在死锁或锁等待超时时重试，fn 需要可以重复执行
+func Transaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	o := &txOptions{retries: 2, backoff: 50 * time.Millisecond}
+	for _, opt := range opts {
+		opt(o)
+	}
+	parent, _ := ctx.Value(txKey{}).(*txChain)
+	run := func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, &txChain{tx: tx, parent: parent}))
+	}
+	db := o.db
+	if db == nil {
+		db = getDefault()
+		if db == nil {
+			logrus.Panic("gormx: default db is not set, call gormx.SetDefault or gormx.Must first")
+		}
+	}
+	if tx, ok := txFor(ctx, db); ok {
+		return tx.WithContext(ctx).Transaction(run, o.sqlOpts)
+	}
+	db = db.WithContext(ctx)
+	for attempt := 0; ; attempt++ {
+		err := db.Transaction(run, o.sqlOpts)
+		if err == nil || attempt >= o.retries || !retryable(err) {
+			return err
+		}
+		wait := time.Duration(attempt+1) * o.backoff
+		if o.backoff > 0 {
+			wait += time.Duration(rand.Int63n(int64(o.backoff)))
+		}
+		logrus.WithContext(ctx).Warnf("gormx: transaction retry %d/%d after %s: %v", attempt+1, o.retries, wait, err)
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), err.Error())
+		case <-time.After(wait):
+		}
+	}
+}
+
+// retryable 判断是否为可重试的 MySQL 死锁或锁等待超时错误
+func retryable(err error) bool {
+	var e *mysql.MySQLError
+	return errors.As(err, &e) && (e.Number == mysqlDeadlock || e.Number == mysqlLockWaitTimeout)
+}
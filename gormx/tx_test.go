@@ -0,0 +1,174 @@
+package gormx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestTransaction(t *testing.T) {
+	db := sqliteDB(t)
+	SetDefault(db)
+	defer SetDefault(nil)
+	repo := NewRepository[testOrder](db)
+	errRollback := errors.New("rollback")
+
+	count := func(no string) int64 {
+		var n int64
+		if err := db.Model(&testOrder{}).Where("no = ?", no).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	err := Transaction(context.Background(), func(ctx context.Context) error {
+		if _, ok := FromContext(ctx); !ok {
+			t.Fatal("FromContext() should return the tx")
+		}
+		if err := repo.Create(ctx, &testOrder{No: "commit"}); err != nil {
+			return err
+		}
+		return DB(ctx).Create(&testOrder{No: "commit"}).Error
+	})
+	if err != nil || count("commit") != 2 {
+		t.Fatalf("commit got = %d, error = %v", count("commit"), err)
+	}
+
+	err = Transaction(context.Background(), func(ctx context.Context) error {
+		if err := repo.Create(ctx, &testOrder{No: "rollback"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) || count("rollback") != 0 {
+		t.Fatalf("rollback got = %d, error = %v", count("rollback"), err)
+	}
+
+	// 内层事务使用保存点，回滚不影响外层
+	err = Transaction(context.Background(), func(ctx context.Context) error {
+		if err := repo.Create(ctx, &testOrder{No: "outer"}); err != nil {
+			return err
+		}
+		err := Transaction(ctx, func(ctx context.Context) error {
+			if err := repo.Create(ctx, &testOrder{No: "inner"}); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Fatalf("inner error = %v", err)
+		}
+		return Transaction(ctx, func(ctx context.Context) error {
+			return repo.Create(ctx, &testOrder{No: "inner2"})
+		})
+	})
+	if err != nil || count("outer") != 1 || count("inner") != 0 || count("inner2") != 1 {
+		t.Fatalf("savepoint got = %d/%d/%d, error = %v", count("outer"), count("inner"), count("inner2"), err)
+	}
+}
+
+func TestTransactionOtherDB(t *testing.T) {
+	db, other := sqliteDB(t), sqliteDB(t)
+	SetDefault(db)
+	defer SetDefault(nil)
+	repo, otherRepo := NewRepository[testOrder](db), NewRepository[testOrder](other)
+	errRollback := errors.New("rollback")
+
+	count := func(db *gorm.DB, no string) int64 {
+		var n int64
+		if err := db.Model(&testOrder{}).Where("no = ?", no).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	err := Transaction(context.Background(), func(ctx context.Context) error {
+		err := Transaction(ctx, func(ctx context.Context) error {
+			if tx, _ := FromContext(ctx); tx.Dialector != other.Dialector {
+				t.Fatal("FromContext() should return the tx on other")
+			}
+			if err := otherRepo.Create(ctx, &testOrder{No: "other"}); err != nil {
+				return err
+			}
+			// 外层实例的事务仍然可用
+			if err := repo.Create(ctx, &testOrder{No: "default"}); err != nil {
+				return err
+			}
+			return DB(ctx).Create(&testOrder{No: "default"}).Error
+		}, WithTxDB(other))
+		if err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Transaction() error = %v", err)
+	}
+	// other 上的事务独立提交，外层回滚只影响默认实例
+	if count(other, "other") != 1 || count(db, "default") != 0 || count(db, "other") != 0 {
+		t.Fatalf("got other = %d, default = %d", count(other, "other"), count(db, "default"))
+	}
+
+	// 不在默认实例的事务中时，DB(ctx) 不会返回其他实例的事务
+	err = Transaction(context.Background(), func(ctx context.Context) error {
+		if DB(ctx).Dialector != db.Dialector {
+			t.Fatal("DB() should return the default db")
+		}
+		return nil
+	}, WithTxDB(other))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTransactionRetry(t *testing.T) {
+	db := sqliteDB(t)
+	deadlock := &mysql.MySQLError{Number: mysqlDeadlock, Message: "Deadlock found"}
+	lockWait := &mysql.MySQLError{Number: mysqlLockWaitTimeout, Message: "Lock wait timeout exceeded"}
+	tests := []struct {
+		name      string
+		opts      []TxOption
+		errs      []error
+		nested    bool
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "retry until success", errs: []error{deadlock, lockWait, nil}, wantCalls: 3},
+		{name: "budget exhausted", errs: []error{deadlock, deadlock, deadlock, nil}, wantCalls: 3, wantErr: deadlock},
+		{name: "custom budget", opts: []TxOption{WithTxRetry(3)}, errs: []error{deadlock, deadlock, deadlock, nil}, wantCalls: 4},
+		{name: "no retry", opts: []TxOption{WithTxRetry(0)}, errs: []error{deadlock, nil}, wantCalls: 1, wantErr: deadlock},
+		{name: "not retryable", errs: []error{&mysql.MySQLError{Number: 1062}, nil}, wantCalls: 1, wantErr: &mysql.MySQLError{Number: 1062}},
+		{name: "nested is not retried", errs: []error{deadlock, nil}, nested: true, wantCalls: 1, wantErr: deadlock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			fn := func(ctx context.Context) error {
+				calls++
+				return tt.errs[calls-1]
+			}
+			opts := append([]TxOption{WithTxDB(db), WithTxBackoff(0)}, tt.opts...)
+			var err error
+			if tt.nested {
+				err = Transaction(context.Background(), func(ctx context.Context) error {
+					return Transaction(ctx, fn, opts...)
+				}, WithTxDB(db), WithTxRetry(0))
+			} else {
+				err = Transaction(context.Background(), fn, opts...)
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("calls got = %d, want %d", calls, tt.wantCalls)
+			}
+			var got, want *mysql.MySQLError
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if tt.wantErr != nil && (!errors.As(err, &got) || !errors.As(tt.wantErr, &want) || got.Number != want.Number) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
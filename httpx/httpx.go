@@ -5,7 +5,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/lascape/gopkg/envx"
 	"io"
 	"net/http"
 	"net/url"
@@ -13,6 +12,11 @@ import (
 	"time"
 )
 
+// Signer 在请求发出前对请求签名，body 为完整的请求体
+type Signer interface {
+	Sign(req *http.Request, body []byte) error
+}
+
 type Client struct {
 	req          *http.Request
 	body         io.Reader
@@ -21,6 +25,7 @@ type Client struct {
 	uri          string
 	startTime    time.Time
 	mustHttpCode int
+	signer       Signer
 }
 
 func NewWithRequest(req *http.Request, uri string) *Client {
@@ -48,6 +53,12 @@ func (h *Client) SetHeaders(hs map[string]string) *Client {
 	return h
 }
 
+// SetSigner 设置请求签名器，例如 authx.NewSigner
+func (h *Client) SetSigner(s Signer) *Client {
+	h.signer = s
+	return h
+}
+
 func (h *Client) SetTimeout(t time.Duration) *Client {
 	h.timeout = t
 	return h
@@ -154,20 +165,13 @@ func (h *Client) request(ctx context.Context, method string) *Response {
 		defer cancel()
 	}
 
-	var curl string
-	if envx.PKG_HTTPX_BCURL {
-		var data = ""
-		if h.body != nil {
-			t, _ := io.ReadAll(h.body)
-			data = string(t)
-			h.body = bytes.NewReader(t)
-		}
-
-		curl = buildCurl(h.uri, method, data, h.req.Header, h.req.Cookies())
+	var data []byte
+	if h.body != nil && (bcurl.Load() || h.signer != nil) {
+		data, _ = io.ReadAll(h.body)
+		h.body = bytes.NewReader(data)
 	}
 
 	resp := &Response{
-		curl:      curl,
 		startTime: h.startTime,
 		errs:      new(strings.Builder),
 	}
@@ -185,6 +189,19 @@ func (h *Client) request(ctx context.Context, method string) *Response {
 		req.AddCookie(cookie)
 	}
 
+	if h.signer != nil {
+		if err = h.signer.Sign(req, data); err != nil {
+			h.errs.WriteString(err.Error())
+			h.errs.WriteString(";")
+			resp.errs.WriteString(h.errs.String())
+			return resp
+		}
+	}
+
+	if bcurl.Load() {
+		resp.curl = buildCurl(h.uri, method, string(data), req.Header, h.req.Cookies())
+	}
+
 	res, err := defaultHttp.Do(req)
 	if err != nil {
 		// https://stackoverflow.com/questions/28046100/golang-http-concurrent-requests-post-eof
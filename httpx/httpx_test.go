@@ -2,10 +2,51 @@ package httpx
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/lascape/gopkg/envx"
 )
 
 func TestGet(t *testing.T) {
 	resp := New("http://www.baidu.com").Get(context.Background())
 	t.Log(resp.GetBodyString())
 }
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pkg.yaml")
+	if err := os.WriteFile(path, []byte("PKG_HTTPX_BCURL: false\nPKG_HTTPX_ENGRESS: http://127.0.0.1:8888\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := envx.NewWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	defer SetProxy(envx.PKG_HTTPX_ENGRESS, envx.PKG_HTTPX_ENGRESS_IGNORE)
+	defer SetBCurl(envx.PKG_HTTPX_BCURL)
+	Watch(w)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	if u, _ := proxyURL()(req); u == nil || u.Host != "127.0.0.1:8888" || bcurl.Load() {
+		t.Fatalf("proxy got = %v, bcurl = %v", u, bcurl.Load())
+	}
+
+	if err = os.WriteFile(path, []byte("PKG_HTTPX_BCURL: true\nPKG_HTTPX_ENGRESS: http://127.0.0.1:8888\nPKG_HTTPX_ENGRESS_IGNORE: example.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := proxyURL()(req); u != nil || !bcurl.Load() {
+		t.Fatalf("proxy got = %v, bcurl = %v", u, bcurl.Load())
+	}
+	for _, s := range envx.Dump() {
+		if s.Name == "PKG_HTTPX_ENGRESS_IGNORE" && (s.Value != "example.com" || s.Source != envx.SourceFile) {
+			t.Fatalf("Dump() got = %+v", s)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -34,11 +35,26 @@ var defaultHttp = &http.Client{
 	},
 }
 
-func proxyURL() func(*http.Request) (*url.URL, error) {
-	urls := strings.Split(envx.PKG_HTTPX_ENGRESS, ",")
+type proxyConfig struct {
+	ips     []*url.URL
+	noproxy []string
+}
+
+var (
+	proxies atomic.Value // *proxyConfig
+	bcurl   atomic.Bool
+)
+
+func init() {
+	SetProxy(envx.PKG_HTTPX_ENGRESS, envx.PKG_HTTPX_ENGRESS_IGNORE)
+	SetBCurl(envx.PKG_HTTPX_BCURL)
+}
+
+// SetProxy 设置出口代理列表与不走代理的 host，均以逗号分隔，可在运行时修改
+func SetProxy(engress, ignore string) {
 	var ips []*url.URL
-	for _, s := range urls {
-		parse, err := url.Parse(s)
+	for _, s := range strings.Split(engress, ",") {
+		parse, err := url.Parse(strings.TrimSpace(s))
 		if err != nil {
 			continue
 		}
@@ -48,21 +64,36 @@ func proxyURL() func(*http.Request) (*url.URL, error) {
 
 		ips = append(ips, parse)
 	}
-	if len(ips) == 0 {
-		return nil
+	proxies.Store(&proxyConfig{ips: ips, noproxy: strings.Split(ignore, ",")})
+}
+
+// SetBCurl 设置是否为请求生成 curl 命令，可在运行时修改
+func SetBCurl(enable bool) {
+	bcurl.Store(enable)
+}
+
+// Watch 从 envx.Watcher 读取 PKG_HTTPX_BCURL、PKG_HTTPX_ENGRESS 与 PKG_HTTPX_ENGRESS_IGNORE，变化时立即生效
+func Watch(w *envx.Watcher) {
+	w.BindBool("PKG_HTTPX_BCURL", true, SetBCurl)
+	setProxy := func(envx.Value) {
+		SetProxy(w.Get("PKG_HTTPX_ENGRESS", "").String(), w.Get("PKG_HTTPX_ENGRESS_IGNORE", "").String())
 	}
-	noproxy := strings.Split(envx.PKG_HTTPX_ENGRESS_IGNORE, ",")
-	r := rand.NewSource(time.Now().Unix())
+	w.Bind("PKG_HTTPX_ENGRESS", "", setProxy)
+	w.Bind("PKG_HTTPX_ENGRESS_IGNORE", "", setProxy)
+}
+
+func proxyURL() func(*http.Request) (*url.URL, error) {
 	return func(req *http.Request) (*url.URL, error) {
-		if len(ips) == 0 {
+		conf, _ := proxies.Load().(*proxyConfig)
+		if conf == nil || len(conf.ips) == 0 {
 			return nil, nil
 		}
-		for _, no := range noproxy {
+		for _, no := range conf.noproxy {
 			if no == req.URL.Host {
 				return nil, nil
 			}
 		}
-		ip := ips[r.Int63()%int64(len(ips))]
+		ip := conf.ips[rand.Intn(len(conf.ips))]
 		fmt.Printf("req host:%s,proxy:%s\n", req.URL.Host, ip)
 		return ip, nil
 	}
@@ -31,3 +31,15 @@ func Init(srvName string) {
 func Writer() io.Writer {
 	return writer
 }
+
+// Watch 从 envx.Watcher 读取 PKG_LOGX_LEVEL，变化时立即调整日志级别
+func Watch(w *envx.Watcher) {
+	w.BindE("PKG_LOGX_LEVEL", envx.PKG_LOGX_LEVEL.String(), func(v envx.Value) error {
+		level, err := logrus.ParseLevel(v.String())
+		if err != nil {
+			return err
+		}
+		logrus.SetLevel(level)
+		return nil
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/lascape/gopkg/envx"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -35,9 +36,23 @@ func Run(opts ...Option) {
 	for _, o := range opts {
 		o(&options)
 	}
+	// 启动前执行各个包登记的安全检查，例如生产环境禁止使用默认密钥
+	if err := envx.Verify(); err != nil {
+		logrus.Fatalf("service %s refused to start: %v", options.conf.ServiceName, err)
+	}
 	options.run()
 }
 
+// WithConfig 设置服务配置，Env 不为空时作为 envx.Current 的配置值
+func WithConfig(c Config) Option {
+	return func(o *Options) {
+		o.conf = c
+		if c.Env != "" {
+			envx.SetCurrent(c.Env)
+		}
+	}
+}
+
 func WithServer(srv ...Server) Option {
 	return func(o *Options) { o.servers = append(o.servers, srv...) }
 }
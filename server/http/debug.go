@@ -0,0 +1,36 @@
+package httpServer
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lascape/gopkg/envx"
+	"github.com/lascape/gopkg/response"
+)
+
+// DebugConfig 挂载 GET /debug/config，返回 envx 登记的全部变量及其生效值，Secret 变量会被掩码。
+// 使用 AddRouter(DebugConfig) 启用，envx.IsProd() 时返回 404；生产环境需要时使用 DebugConfigWith 加上鉴权中间件
+func DebugConfig(engine *gin.Engine) {
+	debugConfig(engine)
+}
+
+// DebugConfigWith 与 DebugConfig 相同，请求先经过 middleware，生产环境也会响应，
+// 例如 AddRouter(DebugConfigWith(authx.Middleware(auth)))
+func DebugConfigWith(middleware ...gin.HandlerFunc) Register {
+	return func(engine *gin.Engine) {
+		debugConfig(engine, middleware...)
+	}
+}
+
+func debugConfig(engine *gin.Engine, middleware ...gin.HandlerFunc) {
+	guarded := len(middleware) > 0
+	handlers := append(append([]gin.HandlerFunc{}, middleware...), func(ctx *gin.Context) {
+		// 每次请求时判断，运行环境可能在挂载路由之后才由配置确定
+		if !guarded && envx.IsProd() {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		response.Success(ctx, envx.Dump())
+	})
+	engine.GET("/debug/config", handlers...)
+}
@@ -18,8 +18,8 @@ func ValueRealIP(ctx context.Context) string {
 
 func XRealIp(ctx *gin.Context) {
 	ip := ctx.GetHeader("X-Forwarded-For")
-	if ip == "" { //如果IP为空，则默认执行以下操作
-		ip = "127.0.0.1"
+	if ip == "" { //如果IP为空，则使用 gin 解析的客户端地址，不能用固定值，否则限流等按 IP 统计的功能会把所有客户端算作同一个
+		ip = ctx.ClientIP()
 	}
 	ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), ctxWithRealIp, ip))
 	ctx.Set(ctxWithRealIp, ip)